@@ -0,0 +1,415 @@
+package field
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+type valueKind int
+
+const (
+	kindNone valueKind = iota
+	kindInt32
+	kindInt64
+	kindFloat64
+	kindBool
+	kindTime
+	kindJSON
+	kindString
+)
+
+// column stores the values of a field in a slice of the type of its values,
+// avoiding boxing each value in an interface. The column starts with the
+// type of the first non null value and is promoted when a value of another
+// type is appended: int32 to int64 to float64 for numbers, otherwise to
+// string. Null values are stored as zero values and recorded in nulls.
+type column struct {
+	kind     valueKind
+	mixed    bool
+	length   int
+	capacity int
+	nulls    []bool
+
+	// textBytes is the length of the stored strings and JSON values
+	textBytes int
+
+	int32s   []int32
+	int64s   []int64
+	float64s []float64
+	bools    []bool
+	times    []time.Time
+	jsons    []json.RawMessage
+	strings  []string
+}
+
+func (c *column) appendNull() {
+
+	switch c.kind {
+	case kindInt32:
+		c.int32s = append(c.int32s, 0)
+	case kindInt64:
+		c.int64s = append(c.int64s, 0)
+	case kindFloat64:
+		c.float64s = append(c.float64s, 0)
+	case kindBool:
+		c.bools = append(c.bools, false)
+	case kindTime:
+		c.times = append(c.times, time.Time{})
+	case kindJSON:
+		c.jsons = append(c.jsons, nil)
+	case kindString:
+		c.strings = append(c.strings, "")
+	}
+	c.added(true)
+}
+
+func (c *column) appendInt32(value int32) {
+
+	switch c.store(kindInt32) {
+	case kindInt32:
+		c.int32s = append(c.int32s, value)
+	case kindInt64:
+		c.int64s = append(c.int64s, int64(value))
+	case kindFloat64:
+		c.float64s = append(c.float64s, float64(value))
+	default:
+		c.appendText(strconv.FormatInt(int64(value), 10))
+	}
+	c.added(false)
+}
+
+func (c *column) appendInt64(value int64) {
+
+	switch c.store(kindInt64) {
+	case kindInt64:
+		c.int64s = append(c.int64s, value)
+	case kindFloat64:
+		c.float64s = append(c.float64s, float64(value))
+	default:
+		c.appendText(strconv.FormatInt(value, 10))
+	}
+	c.added(false)
+}
+
+func (c *column) appendFloat64(value float64) {
+
+	switch c.store(kindFloat64) {
+	case kindFloat64:
+		c.float64s = append(c.float64s, value)
+	default:
+		c.appendText(fmt.Sprint(value))
+	}
+	c.added(false)
+}
+
+func (c *column) appendBool(value bool) {
+
+	switch c.store(kindBool) {
+	case kindBool:
+		c.bools = append(c.bools, value)
+	default:
+		c.appendText(strconv.FormatBool(value))
+	}
+	c.added(false)
+}
+
+func (c *column) appendTime(value time.Time) {
+
+	switch c.store(kindTime) {
+	case kindTime:
+		c.times = append(c.times, value)
+	default:
+		c.appendText(fmt.Sprint(value))
+	}
+	c.added(false)
+}
+
+func (c *column) appendJSON(value json.RawMessage) {
+
+	switch c.store(kindJSON) {
+	case kindJSON:
+		c.jsons = append(c.jsons, value)
+		c.textBytes += len(value)
+	default:
+		c.appendText(string(value))
+	}
+	c.added(false)
+}
+
+func (c *column) appendString(value string) {
+
+	c.store(kindString)
+	c.appendText(value)
+	c.added(false)
+}
+
+func (c *column) appendText(value string) {
+	c.strings = append(c.strings, value)
+	c.textBytes += len(value)
+}
+
+func (c *column) added(null bool) {
+
+	if null && c.nulls == nil {
+		c.nulls = make([]bool, c.length)
+	}
+
+	if c.nulls != nil {
+		c.nulls = append(c.nulls, null)
+	}
+	c.length++
+}
+
+func (c *column) isNull(i int) bool {
+	return c.nulls != nil && c.nulls[i]
+}
+
+// store prepares the column to store a value of the given kind and returns
+// the kind of the column, promoting the column if necessary.
+func (c *column) store(kind valueKind) valueKind {
+
+	if c.kind == kind {
+		return kind
+	}
+
+	target := kindString
+	switch {
+	case c.kind == kindNone:
+		target = kind
+	case isNumber(c.kind) && isNumber(kind):
+		c.mixed = true
+		target = c.kind
+		if kind > target {
+			target = kind
+		}
+	default:
+		c.mixed = true
+	}
+
+	if target != c.kind {
+		c.promote(target)
+	}
+	return c.kind
+}
+
+func (c *column) promote(kind valueKind) {
+
+	capacity := c.capacity
+	if capacity < c.length {
+		capacity = c.length
+	}
+
+	switch kind {
+	case kindInt32:
+		c.int32s = make([]int32, c.length, capacity)
+	case kindInt64:
+		c.int64s = make([]int64, c.length, capacity)
+		for i, v := range c.int32s {
+			c.int64s[i] = int64(v)
+		}
+	case kindFloat64:
+		c.float64s = make([]float64, c.length, capacity)
+		for i, v := range c.int32s {
+			c.float64s[i] = float64(v)
+		}
+		for i, v := range c.int64s {
+			c.float64s[i] = float64(v)
+		}
+	case kindBool:
+		c.bools = make([]bool, c.length, capacity)
+	case kindTime:
+		c.times = make([]time.Time, c.length, capacity)
+	case kindJSON:
+		c.jsons = make([]json.RawMessage, c.length, capacity)
+	case kindString:
+		c.strings = make([]string, c.length, capacity)
+		c.textBytes = 0
+		for i := range c.strings {
+			if !c.isNull(i) {
+				c.strings[i] = c.stringAt(i)
+				c.textBytes += len(c.strings[i])
+			}
+		}
+	}
+
+	c.release(c.kind)
+	c.kind = kind
+}
+
+func (c *column) release(kind valueKind) {
+
+	switch kind {
+	case kindInt32:
+		c.int32s = nil
+	case kindInt64:
+		c.int64s = nil
+	case kindFloat64:
+		c.float64s = nil
+	case kindBool:
+		c.bools = nil
+	case kindTime:
+		c.times = nil
+	case kindJSON:
+		c.jsons = nil
+	}
+}
+
+func (c *column) stringAt(i int) string {
+
+	switch c.kind {
+	case kindInt32:
+		return strconv.FormatInt(int64(c.int32s[i]), 10)
+	case kindInt64:
+		return strconv.FormatInt(c.int64s[i], 10)
+	case kindFloat64:
+		return fmt.Sprint(c.float64s[i])
+	case kindBool:
+		return strconv.FormatBool(c.bools[i])
+	case kindTime:
+		return fmt.Sprint(c.times[i])
+	case kindJSON:
+		return string(c.jsons[i])
+	case kindString:
+		return c.strings[i]
+	default:
+		return ""
+	}
+}
+
+// size returns an estimate of the memory used by the values of the column
+// in bytes, counting the headers of strings and JSON values on 64 bit
+// platforms.
+func (c *column) size() int {
+
+	var width int
+	switch c.kind {
+	case kindInt32:
+		width = 4
+	case kindInt64, kindFloat64:
+		width = 8
+	case kindBool:
+		width = 1
+	case kindTime, kindJSON:
+		width = 24
+	case kindString:
+		width = 16
+	}
+	return c.length*width + len(c.nulls) + c.textBytes
+}
+
+func (c *column) fieldType() data.FieldType {
+
+	switch c.kind {
+	case kindInt32:
+		return data.FieldTypeInt32
+	case kindInt64:
+		return data.FieldTypeInt64
+	case kindFloat64:
+		return data.FieldTypeFloat64
+	case kindBool:
+		return data.FieldTypeBool
+	case kindTime:
+		return data.FieldTypeTime
+	case kindJSON:
+		return data.FieldTypeJSON
+	default:
+		return data.FieldTypeString
+	}
+}
+
+// values returns the backing slice of the column.
+func (c *column) values() interface{} {
+
+	switch c.kind {
+	case kindInt32:
+		return c.int32s
+	case kindInt64:
+		return c.int64s
+	case kindFloat64:
+		return c.float64s
+	case kindBool:
+		return c.bools
+	case kindTime:
+		return c.times
+	case kindJSON:
+		return c.jsons
+	case kindString:
+		return c.strings
+	default:
+		return make([]string, c.length)
+	}
+}
+
+// nullableValues returns a slice of pointers into the backing slice of the
+// column, nil for null values.
+func (c *column) nullableValues() interface{} {
+
+	switch c.kind {
+	case kindInt32:
+		result := make([]*int32, c.length)
+		for i := range result {
+			if !c.isNull(i) {
+				result[i] = &c.int32s[i]
+			}
+		}
+		return result
+	case kindInt64:
+		result := make([]*int64, c.length)
+		for i := range result {
+			if !c.isNull(i) {
+				result[i] = &c.int64s[i]
+			}
+		}
+		return result
+	case kindFloat64:
+		result := make([]*float64, c.length)
+		for i := range result {
+			if !c.isNull(i) {
+				result[i] = &c.float64s[i]
+			}
+		}
+		return result
+	case kindBool:
+		result := make([]*bool, c.length)
+		for i := range result {
+			if !c.isNull(i) {
+				result[i] = &c.bools[i]
+			}
+		}
+		return result
+	case kindTime:
+		result := make([]*time.Time, c.length)
+		for i := range result {
+			if !c.isNull(i) {
+				result[i] = &c.times[i]
+			}
+		}
+		return result
+	case kindJSON:
+		result := make([]*json.RawMessage, c.length)
+		for i := range result {
+			if !c.isNull(i) {
+				result[i] = &c.jsons[i]
+			}
+		}
+		return result
+	case kindString:
+		result := make([]*string, c.length)
+		for i := range result {
+			if !c.isNull(i) {
+				result[i] = &c.strings[i]
+			}
+		}
+		return result
+	default:
+		return make([]*string, c.length)
+	}
+}
+
+func isNumber(kind valueKind) bool {
+	return kind == kindInt32 || kind == kindInt64 || kind == kindFloat64
+}
@@ -0,0 +1,126 @@
+package field
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestColumnPromotion(t *testing.T) {
+
+	one, two := "1", "true"
+	date := time.Unix(1620586358, 0)
+
+	var tests = []struct {
+		values    []interface{}
+		fieldType data.FieldType
+		mixed     bool
+		want      interface{}
+	}{
+		//Single type
+		{[]interface{}{int32(1), int32(2)}, data.FieldTypeInt32, false, []int32{1, 2}},
+
+		//Widen int32 to int64
+		{[]interface{}{int32(1), int64(2)}, data.FieldTypeInt64, true, []int64{1, 2}},
+
+		//Widen integers to float64
+		{[]interface{}{int32(1), int64(2), float64(3.5)}, data.FieldTypeFloat64, true, []float64{1, 2, 3.5}},
+
+		//Float64 stays float64
+		{[]interface{}{float64(1.5), int32(2)}, data.FieldTypeFloat64, true, []float64{1.5, 2}},
+
+		//Conflict promotes to string
+		{[]interface{}{int32(1), true}, data.FieldTypeString, true, []string{"1", "true"}},
+
+		//Nulls are kept on promotion
+		{[]interface{}{nil, int32(1), nil, int64(2), float64(3)}, data.FieldTypeFloat64, true,
+			[]*float64{nil, float64Ptr(1), nil, float64Ptr(2), float64Ptr(3)}},
+
+		//Nulls are kept on string promotion
+		{[]interface{}{int32(1), nil, true}, data.FieldTypeString, true, []*string{&one, nil, &two}},
+
+		//Only nulls
+		{[]interface{}{nil, nil}, data.FieldTypeString, false, []*string{nil, nil}},
+
+		//Times
+		{[]interface{}{date}, data.FieldTypeTime, false, []time.Time{date}},
+	}
+
+	for _, test := range tests {
+
+		var c column
+		nullable := false
+		for _, value := range test.values {
+			switch value := value.(type) {
+			case nil:
+				nullable = true
+				c.appendNull()
+			case int32:
+				c.appendInt32(value)
+			case int64:
+				c.appendInt64(value)
+			case float64:
+				c.appendFloat64(value)
+			case bool:
+				c.appendBool(value)
+			case time.Time:
+				c.appendTime(value)
+			}
+		}
+
+		got := c.values()
+		if nullable {
+			got = c.nullableValues()
+		}
+
+		if c.fieldType() != test.fieldType || c.mixed != test.mixed || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("column %v = (%v, %v, %v)", test.values, c.fieldType(), c.mixed, got)
+		}
+	}
+}
+
+func TestColumnSize(t *testing.T) {
+
+	var tests = []struct {
+		values []interface{}
+		want   int
+	}{
+		//Empty
+		{[]interface{}{}, 0},
+
+		//Fixed width
+		{[]interface{}{int32(1), int32(2)}, 8},
+
+		//Nulls
+		{[]interface{}{nil, float64(1)}, 18},
+
+		//Strings
+		{[]interface{}{"ab", "cde"}, 37},
+
+		//Promoted to string
+		{[]interface{}{int32(10), "cde"}, 37},
+	}
+
+	for _, test := range tests {
+
+		var c column
+		for _, value := range test.values {
+			switch value := value.(type) {
+			case nil:
+				c.appendNull()
+			case int32:
+				c.appendInt32(value)
+			case float64:
+				c.appendFloat64(value)
+			case string:
+				c.appendString(value)
+			}
+		}
+
+		if got := c.size(); got != test.want {
+			t.Errorf("column %v size() = %v", test.values, got)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package field
+
+import (
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+type ColumnOrder string
+
+const (
+	// OrderFirstSeen orders fields by the first document they appear in.
+	OrderFirstSeen ColumnOrder = "firstSeen"
+
+	// OrderAlphabetical orders fields by name.
+	OrderAlphabetical ColumnOrder = "alphabetical"
+
+	// OrderProjection orders fields by the projection of the query, fields
+	// that are not projected follow in first seen order.
+	OrderProjection ColumnOrder = "projection"
+)
+
+func (o ColumnOrder) IsValid() bool {
+	return o == "" || o == OrderFirstSeen || o == OrderAlphabetical || o == OrderProjection
+}
+
+// OutputColumn selects a field of the result and optionally renames it.
+type OutputColumn struct {
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+}
+
+// selectFields orders, renames and filters the built fields. The selected
+// Columns come first, in order, and are returned even when no document has
+// them so their positions are stable. The remaining fields follow the
+// declared Schema fields in ColumnOrder unless OnlyColumns is set.
+func (fb *FieldBuilder) selectFields(fields []*data.Field) []*data.Field {
+
+	options := fb.options
+	if len(options.Columns) == 0 && len(options.ExcludeColumns) == 0 &&
+		(options.ColumnOrder == "" || options.ColumnOrder == OrderFirstSeen) {
+		return fields
+	}
+
+	excluded := make(map[string]bool)
+	for _, name := range options.ExcludeColumns {
+		excluded[name] = true
+	}
+
+	index := make(map[string]*data.Field)
+	for _, field := range fields {
+		index[field.Name] = field
+	}
+
+	result := make([]*data.Field, 0, len(fields))
+	selected := make(map[string]bool)
+	used := make(map[*data.Field]bool)
+	for _, column := range options.Columns {
+
+		if excluded[column.Name] || selected[column.Name] {
+			continue
+		}
+		selected[column.Name] = true
+
+		field := index[column.Name]
+		if field == nil {
+			field = data.NewFieldFromFieldType(data.FieldTypeNullableString, fb.recordCount)
+			field.Name = column.Name
+		}
+		if column.Alias != "" {
+			field.Name = column.Alias
+		}
+		used[field] = true
+		result = append(result, field)
+	}
+
+	if options.OnlyColumns {
+		return result
+	}
+
+	remaining := make([]*data.Field, 0, len(fields))
+	for _, field := range fields {
+		if !used[field] && !excluded[field.Name] {
+			remaining = append(remaining, field)
+		}
+	}
+
+	rank := fb.columnRank()
+	sort.SliceStable(remaining, func(i, j int) bool {
+
+		rank1, rank2 := rank(remaining[i].Name), rank(remaining[j].Name)
+		if rank1 != rank2 {
+			return rank1 < rank2
+		}
+		return options.ColumnOrder == OrderAlphabetical && remaining[i].Name < remaining[j].Name
+	})
+	return append(result, remaining...)
+}
+
+// columnRank returns the position of a field name in the declared schema
+// followed, for OrderProjection, by the derived schema. Other fields rank
+// after them.
+func (fb *FieldBuilder) columnRank() func(string) int {
+
+	ranks := make(map[string]int)
+	for _, column := range fb.options.Schema {
+		if _, ok := ranks[column.Name]; !ok {
+			ranks[column.Name] = len(ranks)
+		}
+	}
+
+	if fb.options.ColumnOrder == OrderProjection {
+		for _, column := range fb.options.DerivedSchema {
+			if _, ok := ranks[column.Name]; !ok {
+				ranks[column.Name] = len(ranks)
+			}
+		}
+	}
+
+	return func(name string) int {
+		if rank, ok := ranks[name]; ok {
+			return rank
+		}
+		return len(ranks)
+	}
+}
@@ -0,0 +1,92 @@
+package field
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFieldBuilderColumns(t *testing.T) {
+
+	records := []primitive.D{
+		{{Key: "c", Value: "c1"}, {Key: "a", Value: "a1"}},
+		{{Key: "b", Value: "b2"}, {Key: "a", Value: "a2"}},
+	}
+
+	a := func() *data.Field { return data.NewField("a", nil, []string{"a1", "a2"}) }
+	b := func() *data.Field { return data.NewField("b", nil, []*string{nil, stringPtr("b2")}) }
+	c := func() *data.Field { return data.NewField("c", nil, []*string{stringPtr("c1"), nil}) }
+	derived := []Column{{Name: "b"}, {Name: "a"}}
+
+	var tests = []struct {
+		options Options
+		want    []*data.Field
+	}{
+		//First seen order by default
+		{Options{},
+			[]*data.Field{c(), a(), b()}},
+
+		//Alphabetical order
+		{Options{ColumnOrder: OrderAlphabetical},
+			[]*data.Field{a(), b(), c()}},
+
+		//Projection order
+		{Options{ColumnOrder: OrderProjection, DerivedSchema: derived},
+			[]*data.Field{b(), a(), c()}},
+
+		//Projection order without a projection
+		{Options{ColumnOrder: OrderProjection},
+			[]*data.Field{c(), a(), b()}},
+
+		//Declared fields come first
+		{Options{ColumnOrder: OrderAlphabetical, Schema: []Column{{Name: "c"}}},
+			[]*data.Field{c(), a(), b()}},
+
+		//Selected columns with an alias and a missing column
+		{Options{Columns: []OutputColumn{{Name: "b", Alias: "B"}, {Name: "d"}, {Name: "b"}}},
+			[]*data.Field{
+				data.NewField("B", nil, []*string{nil, stringPtr("b2")}),
+				data.NewField("d", nil, []*string{nil, nil}),
+				c(), a(),
+			}},
+
+		//Only selected columns
+		{Options{Columns: []OutputColumn{{Name: "a"}, {Name: "c"}}, OnlyColumns: true},
+			[]*data.Field{a(), c()}},
+
+		//Excluded columns
+		{Options{Columns: []OutputColumn{{Name: "a"}}, ExcludeColumns: []string{"a", "c"}},
+			[]*data.Field{b()}},
+	}
+
+	for _, test := range tests {
+
+		fieldBuilder := NewFieldBuilderWithOptions(5, test.options)
+		for _, record := range records {
+			fieldBuilder.ProcessRecord(record)
+		}
+
+		if got := fieldBuilder.BuildFields(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v fieldBuilder.BuildFields() = %v", test.options, got)
+		}
+	}
+}
+
+func TestFieldBuilderColumnsEmpty(t *testing.T) {
+
+	fieldBuilder := NewFieldBuilderWithOptions(5, Options{
+		DerivedSchema: []Column{{Name: "b"}, {Name: "a", Type: ColumnInt}},
+		Columns:       []OutputColumn{{Name: "a", Alias: "A"}},
+	})
+
+	want := []*data.Field{
+		data.NewField("A", nil, []*int64{}),
+		data.NewField("b", nil, []*string{}),
+	}
+
+	if got := fieldBuilder.BuildFields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("fieldBuilder.BuildFields() = %v", got)
+	}
+}
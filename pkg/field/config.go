@@ -0,0 +1,128 @@
+package field
+
+import (
+	"math"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MetaDocumentId is the _id of a document that holds the configs of fields
+// instead of a row. Each field of the document other than _id is a document
+// with the FieldConfig properties of the field of the same name.
+const MetaDocumentId = "$meta"
+
+// FieldConfig sets how Grafana displays the values of a field.
+type FieldConfig struct {
+	DisplayName string   `json:"displayName"`
+	Description string   `json:"description"`
+	Unit        string   `json:"unit"`
+	Decimals    *uint16  `json:"decimals"`
+	Min         *float64 `json:"min"`
+	Max         *float64 `json:"max"`
+}
+
+// merge returns the config with the properties set in other replacing its
+// own.
+func (c FieldConfig) merge(other FieldConfig) FieldConfig {
+
+	if other.DisplayName != "" {
+		c.DisplayName = other.DisplayName
+	}
+	if other.Description != "" {
+		c.Description = other.Description
+	}
+	if other.Unit != "" {
+		c.Unit = other.Unit
+	}
+	if other.Decimals != nil {
+		c.Decimals = other.Decimals
+	}
+	if other.Min != nil {
+		c.Min = other.Min
+	}
+	if other.Max != nil {
+		c.Max = other.Max
+	}
+	return c
+}
+
+// dataConfig returns the config of a data.Field, nil when no property is
+// set.
+func (c FieldConfig) dataConfig() *data.FieldConfig {
+
+	if c == (FieldConfig{}) {
+		return nil
+	}
+
+	config := &data.FieldConfig{
+		DisplayNameFromDS: c.DisplayName,
+		Description:       c.Description,
+		Unit:              c.Unit,
+		Decimals:          c.Decimals,
+	}
+	if c.Min != nil {
+		min := data.ConfFloat64(*c.Min)
+		config.Min = &min
+	}
+	if c.Max != nil {
+		max := data.ConfFloat64(*c.Max)
+		config.Max = &max
+	}
+	return config
+}
+
+func isMetaDocument(record primitive.D) bool {
+	return len(record) > 0 && record[0].Key == "_id" && record[0].Value == MetaDocumentId
+}
+
+// processMeta reads the field configs of a meta document, later documents
+// replace the properties set by earlier ones.
+func (fb *FieldBuilder) processMeta(record primitive.D) {
+
+	for _, e := range record[1:] {
+
+		properties, ok := e.Value.(primitive.D)
+		if !ok {
+			continue
+		}
+
+		var config FieldConfig
+		for _, p := range properties {
+			switch p.Key {
+			case "displayName":
+				config.DisplayName, _ = p.Value.(string)
+			case "description":
+				config.Description, _ = p.Value.(string)
+			case "unit":
+				config.Unit, _ = p.Value.(string)
+			case "decimals":
+				if decimals, ok := castInt(p.Value); ok && decimals >= 0 && decimals <= math.MaxUint16 {
+					value := uint16(decimals)
+					config.Decimals = &value
+				}
+			case "min":
+				if min, ok := castFloat(p.Value); ok {
+					config.Min = &min
+				}
+			case "max":
+				if max, ok := castFloat(p.Value); ok {
+					config.Max = &max
+				}
+			}
+		}
+		fb.metaConfigs[e.Key] = fb.metaConfigs[e.Key].merge(config)
+	}
+}
+
+// applyConfigs sets the configs of the fields from meta documents and the
+// FieldConfig option, which takes precedence.
+func (fb *FieldBuilder) applyConfigs(fields []*data.Field) {
+
+	for _, field := range fields {
+		config := fb.metaConfigs[field.Name].merge(fb.options.FieldConfig[field.Name])
+		if dataConfig := config.dataConfig(); dataConfig != nil {
+			field.Config = dataConfig
+		}
+	}
+}
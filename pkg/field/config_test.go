@@ -0,0 +1,82 @@
+package field
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFieldBuilderFieldConfig(t *testing.T) {
+
+	decimals := uint16(2)
+	min, max := data.ConfFloat64(0), data.ConfFloat64(100)
+	minValue, maxValue := float64(0), float64(100)
+
+	var tests = []struct {
+		options Options
+		records []primitive.D
+		want    []*data.Field
+	}{
+		//No config
+		{Options{},
+			[]primitive.D{{{Key: "cpu", Value: 1.5}}},
+			[]*data.Field{
+				data.NewField("cpu", nil, []float64{1.5}),
+			}},
+
+		//Config from the options
+		{Options{FieldConfig: map[string]FieldConfig{
+			"cpu":   {DisplayName: "CPU", Description: "CPU usage", Unit: "percent", Decimals: &decimals, Min: &minValue, Max: &maxValue},
+			"other": {Unit: "bytes"},
+		}},
+			[]primitive.D{{{Key: "cpu", Value: 1.5}}},
+			[]*data.Field{
+				data.NewField("cpu", nil, []float64{1.5}).SetConfig(&data.FieldConfig{
+					DisplayNameFromDS: "CPU", Description: "CPU usage", Unit: "percent", Decimals: &decimals, Min: &min, Max: &max}),
+			}},
+
+		//Config from meta documents
+		{Options{},
+			[]primitive.D{
+				{{Key: "_id", Value: MetaDocumentId}, {Key: "cpu", Value: primitive.D{{Key: "unit", Value: "percent"}, {Key: "decimals", Value: int32(2)}}}},
+				{{Key: "cpu", Value: 1.5}},
+				{{Key: "_id", Value: MetaDocumentId}, {Key: "cpu", Value: primitive.D{{Key: "min", Value: int32(0)}, {Key: "max", Value: 100.0}}}, {Key: "host", Value: "ignored"}},
+			},
+			[]*data.Field{
+				data.NewField("cpu", nil, []float64{1.5}).SetConfig(&data.FieldConfig{
+					Unit: "percent", Decimals: &decimals, Min: &min, Max: &max}),
+			}},
+
+		//Options take precedence over meta documents
+		{Options{FieldConfig: map[string]FieldConfig{"cpu": {Unit: "percentunit"}}},
+			[]primitive.D{
+				{{Key: "_id", Value: MetaDocumentId}, {Key: "cpu", Value: primitive.D{{Key: "unit", Value: "percent"}, {Key: "displayName", Value: "CPU"}}}},
+				{{Key: "cpu", Value: 1.5}},
+			},
+			[]*data.Field{
+				data.NewField("cpu", nil, []float64{1.5}).SetConfig(&data.FieldConfig{
+					DisplayNameFromDS: "CPU", Unit: "percentunit"}),
+			}},
+
+		//Config of an empty result
+		{Options{DerivedSchema: []Column{{Name: "cpu", Type: ColumnFloat}}, FieldConfig: map[string]FieldConfig{"cpu": {Unit: "percent"}}},
+			[]primitive.D{},
+			[]*data.Field{
+				data.NewField("cpu", nil, []*float64{}).SetConfig(&data.FieldConfig{Unit: "percent"}),
+			}},
+	}
+
+	for _, test := range tests {
+
+		fieldBuilder := NewFieldBuilderWithOptions(5, test.options)
+		for _, record := range test.records {
+			fieldBuilder.ProcessRecord(record)
+		}
+
+		if got := fieldBuilder.BuildFields(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v fieldBuilder.BuildFields() = %v", test.records, got)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package field
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Decimal128Mode string
+
+const (
+	// Decimal128String renders Decimal128 values as exact strings.
+	Decimal128String Decimal128Mode = "string"
+
+	// Decimal128Float converts Decimal128 values to float64 so they can be
+	// summed and graphed.
+	Decimal128Float Decimal128Mode = "float"
+)
+
+func (m Decimal128Mode) IsValid() bool {
+	return m == "" || m == Decimal128String || m == Decimal128Float
+}
+
+// decimalToFloat converts a Decimal128 to the nearest float64. The second
+// result is false when significant digits were lost, including NaN and
+// Infinity values. Decimals such as 0.1 that have no exact binary form but
+// are the shortest decimal form of their float64 lose no digits.
+func decimalToFloat(value primitive.Decimal128) (float64, bool) {
+
+	if value.IsNaN() {
+		return math.NaN(), false
+	}
+
+	if inf := value.IsInf(); inf != 0 {
+		return math.Inf(inf), false
+	}
+
+	rat, ok := new(big.Rat).SetString(value.String())
+	if !ok {
+		return math.NaN(), false
+	}
+
+	f, _ := rat.Float64()
+	shortest, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return f, ok && shortest.Cmp(rat) == 0
+}
@@ -0,0 +1,54 @@
+package field
+
+import (
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDecimalToFloat(t *testing.T) {
+
+	var tests = []struct {
+		value string
+		want1 float64
+		want2 bool
+	}{
+		//integer
+		{"555", 555, true},
+
+		//exact fraction
+		{"12.5", 12.5, true},
+
+		//exponent
+		{"1.5E+3", 1500, true},
+
+		//negative
+		{"-0.25", -0.25, true},
+
+		//fractions without an exact binary form keep their digits
+		{"0.1", 0.1, true},
+		{"12.34", 12.34, true},
+
+		//too many significant digits
+		{"1234567890.123456789012345", 1234567890.123456789012345, false},
+
+		//infinity
+		{"Infinity", math.Inf(1), false},
+
+		//negative infinity
+		{"-Infinity", math.Inf(-1), false},
+	}
+
+	for _, test := range tests {
+		decimal, _ := primitive.ParseDecimal128(test.value)
+		if got1, got2 := decimalToFloat(decimal); got1 != test.want1 || got2 != test.want2 {
+			t.Errorf("decimalToFloat(%s) = (%v,%v)", test.value, got1, got2)
+		}
+	}
+
+	nan, _ := primitive.ParseDecimal128("NaN")
+	if got1, got2 := decimalToFloat(nan); !math.IsNaN(got1) || got2 {
+		t.Errorf("decimalToFloat(NaN) = (%v,%v)", got1, got2)
+	}
+}
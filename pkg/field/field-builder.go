@@ -2,8 +2,9 @@ package field
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"reflect"
+	"math"
 	"strings"
 	"time"
 
@@ -15,104 +16,236 @@ import (
 type field struct {
 	Name     string
 	Nullable bool
-	Values   []interface{}
+
+	values column
+	first  interface{}
+
+	// declared is the column the values are cast to, if any
+	declared     *Column
+	castFailures int
+
+	inexactDecimals   int
+	nonFiniteDecimals int
 }
 
 func newField(name string, capacity int) *field {
 
 	return &field{
 		Name:   name,
-		Values: make([]interface{}, 0, capacity),
+		values: column{capacity: capacity},
 	}
 }
 
 func (f *field) append(value interface{}) {
-	f.Nullable = f.Nullable || value == nil
-	f.Values = append(f.Values, asFieldValue(value))
+
+	// dates are the most common values of time series, convert them without
+	// boxing the time.Time returned by asFieldValue
+	if value, ok := value.(primitive.DateTime); ok {
+		f.appendTime(value.Time())
+		return
+	}
+
+	switch value := asFieldValue(value).(type) {
+	case nil:
+		f.appendNull()
+	case int32:
+		f.appendInt32(value)
+	case int64:
+		f.appendInt64(value)
+	case float64:
+		f.appendFloat64(value)
+	case bool:
+		f.appendBool(value)
+	case time.Time:
+		f.appendTime(value)
+	case string:
+		f.appendString(value)
+	case json.RawMessage:
+		if f.first == nil {
+			f.first = value
+		}
+		f.values.appendJSON(value)
+	default:
+		if f.first == nil {
+			f.first = value
+		}
+		f.values.appendString(fmt.Sprintf("%v", value))
+	}
 }
 
-func (f *field) expandTo(size int) {
+// declare casts the values of the field to the type of the column. Declared
+// fields are nullable so their type does not depend on the values.
+func (f *field) declare(column Column) {
 
-	if len(f.Values) < size {
-		f.Nullable = true
-		f.Values = append(f.Values, make([]interface{}, size-len(f.Values))...)
+	if column.Type == "" {
+		column.Type = ColumnString
 	}
+	f.declared = &column
+	f.Nullable = true
+	f.values.promote(column.Type.kind())
 }
 
-func (f *field) fieldType() data.FieldType {
+// appendCast appends a value cast to the declared type, values that cannot
+// be cast are appended as nulls.
+func (f *field) appendCast(value interface{}) {
 
-	fieldType := data.FieldTypeString
+	if value == nil {
+		f.appendNull()
+		return
+	}
 
-	if f.allValuesSameType() {
-		firstValue := f.firstValue()
-		if firstValue != nil {
-			fieldType = fieldTypeFromValue(firstValue)
+	ok := true
+	switch f.declared.Type {
+	case ColumnInt:
+		var v int64
+		if v, ok = castInt(value); ok {
+			f.appendInt64(v)
+		}
+	case ColumnFloat:
+		var v float64
+		if v, ok = castFloat(value); ok {
+			f.appendFloat64(v)
+		}
+	case ColumnBool:
+		var v bool
+		if v, ok = castBool(value); ok {
+			f.appendBool(v)
+		}
+	case ColumnTime:
+		var v time.Time
+		if v, ok = castTime(value, *f.declared); ok {
+			f.appendTime(v)
 		}
+	default:
+		f.appendString(StringValue(value))
 	}
 
-	if f.Nullable {
-		fieldType = fieldType.NullableType()
+	if !ok {
+		f.castFailures++
+		f.appendNull()
 	}
+}
 
-	return fieldType
+func (f *field) appendNull() {
+	f.Nullable = true
+	f.values.appendNull()
 }
 
-func (f *field) build() *data.Field {
+func (f *field) appendInt32(value int32) {
 
-	fieldType := f.fieldType()
-	field := data.NewFieldFromFieldType(fieldType, len(f.Values))
-	field.Name = f.Name
-	for i, v := range f.Values {
-		v = convertValue(fieldType, v)
-		field.Set(i, v)
+	if f.first == nil {
+		f.first = value
 	}
-	return field
+	f.values.appendInt32(value)
 }
 
-func (f *field) allValuesSameType() bool {
+func (f *field) appendInt64(value int64) {
 
-	result := true
-	var lastType reflect.Type = nil
+	if f.first == nil {
+		f.first = value
+	}
+	f.values.appendInt64(value)
+}
 
-	for _, v := range f.Values {
+func (f *field) appendFloat64(value float64) {
 
-		if lastType == nil && v != nil {
-			lastType = reflect.TypeOf(v)
-		} else if v != nil && lastType != reflect.TypeOf(v) {
-			result = false
-			break
-		}
+	if f.first == nil {
+		f.first = value
 	}
-	return result
+	f.values.appendFloat64(value)
 }
 
-func (f *field) firstValue() interface{} {
+func (f *field) appendBool(value bool) {
 
-	var result interface{}
-	for _, v := range f.Values {
-		if v != nil {
-			result = v
-			break
-		}
+	if f.first == nil {
+		f.first = value
 	}
-	return result
+	f.values.appendBool(value)
 }
 
-func convertValue(fieldType data.FieldType, value interface{}) interface{} {
+func (f *field) appendTime(value time.Time) {
+
+	if f.first == nil {
+		f.first = value
+	}
+	f.values.appendTime(value)
+}
 
-	result := value
+func (f *field) appendString(value string) {
 
-	if fieldType.NullableType() == data.FieldTypeNullableString && result != nil {
-		resultType := fieldTypeFromValue(result).NullableType()
-		if resultType != data.FieldTypeNullableString {
-			result = fmt.Sprintf("%v", result)
-		}
+	if f.first == nil {
+		f.first = value
 	}
+	f.values.appendString(value)
+}
+
+func (f *field) appendDecimal(value primitive.Decimal128) {
 
-	if fieldType.Nullable() {
-		result = asNullableValue(result)
+	result, exact := decimalToFloat(value)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		f.nonFiniteDecimals++
+	} else if !exact {
+		f.inexactDecimals++
 	}
-	return result
+	f.append(result)
+}
+
+func (f *field) notices() []data.Notice {
+
+	notices := make([]data.Notice, 0)
+	if f.castFailures > 0 {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("%d value(s) in field %q could not be converted to %s and were replaced by null", f.castFailures, f.Name, f.declared.Type),
+		})
+	}
+	if f.inexactDecimals > 0 {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("%d Decimal128 value(s) in field %q lost precision when converted to float64", f.inexactDecimals, f.Name),
+		})
+	}
+	if f.nonFiniteDecimals > 0 {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("%d Decimal128 value(s) in field %q are NaN or Infinity", f.nonFiniteDecimals, f.Name),
+		})
+	}
+	return notices
+}
+
+func (f *field) expandTo(size int) {
+
+	for f.values.length < size {
+		f.appendNull()
+	}
+}
+
+func (f *field) fieldType() data.FieldType {
+
+	fieldType := f.values.fieldType()
+	if f.Nullable {
+		fieldType = fieldType.NullableType()
+	}
+	return fieldType
+}
+
+// build hands the typed values of the field to a data.Field without
+// copying them.
+func (f *field) build() *data.Field {
+
+	if f.Nullable {
+		return data.NewField(f.Name, nil, f.values.nullableValues())
+	}
+	return data.NewField(f.Name, nil, f.values.values())
+}
+
+func (f *field) allValuesSameType() bool {
+	return !f.values.mixed
+}
+
+func (f *field) firstValue() interface{} {
+	return f.first
 }
 
 func asFieldValue(value interface{}) interface{} {
@@ -158,147 +291,297 @@ func asFieldValue(value interface{}) interface{} {
 	}
 }
 
-func asJsonString(value interface{}) string {
+// StringValueWithOptions returns the text of a BSON value as it is rendered
+// in a string field built with the options, so binary UUIDs are rendered in
+// the UUID representation of the options.
+func StringValueWithOptions(value interface{}, options Options) string {
 
-	json, err := bson.MarshalExtJSON(value, false, false)
-	if err != nil {
-		return fmt.Sprintf("%v", value)
+	if binary, ok := value.(primitive.Binary); ok {
+		if uuid, ok := uuidString(binary, options.UUIDRepresentation); ok {
+			return uuid
+		}
 	}
-	return string(json)
+	return StringValue(value)
 }
 
-func asNullableValue(value interface{}) interface{} {
-	switch value := value.(type) {
+// StringValue returns the text of a BSON value as it is rendered in a
+// string field.
+func StringValue(value interface{}) string {
+
+	switch value := asFieldValue(value).(type) {
+
+	case nil:
+		return ""
 
-	case int8:
-		return &value
-	case int16:
-		return &value
-	case int32:
-		return &value
-	case int64:
-		return &value
-	case uint8:
-		return &value
-	case uint16:
-		return &value
-	case uint32:
-		return &value
-	case uint64:
-		return &value
-	case float32:
-		return &value
-	case float64:
-		return &value
 	case string:
-		return &value
-	case bool:
-		return &value
+		return value
+
 	case time.Time:
-		return &value
+		return value.UTC().Format(time.RFC3339Nano)
+
+	case json.RawMessage:
+		return string(value)
+
 	default:
-		return value
+		return fmt.Sprintf("%v", value)
 	}
 }
 
-func fieldTypeFromValue(value interface{}) data.FieldType {
-	switch value.(type) {
-	// ints
-	case int8:
-		return data.FieldTypeInt8
-	case *int8:
-		return data.FieldTypeNullableInt8
-	case int16:
-		return data.FieldTypeInt16
-	case *int16:
-		return data.FieldTypeNullableInt16
-	case int32:
-		return data.FieldTypeInt32
-	case *int32:
-		return data.FieldTypeNullableInt32
-	case int64:
-		return data.FieldTypeInt64
-	case *int64:
-		return data.FieldTypeNullableInt64
-
-	// uints
-	case uint8:
-		return data.FieldTypeUint8
-	case *uint8:
-		return data.FieldTypeNullableUint8
-	case uint16:
-		return data.FieldTypeUint16
-	case *uint16:
-		return data.FieldTypeNullableUint16
-	case uint32:
-		return data.FieldTypeUint32
-	case *uint32:
-		return data.FieldTypeNullableUint32
-	case uint64:
-		return data.FieldTypeUint64
-	case *uint64:
-		return data.FieldTypeNullableUint64
-
-	// floats
-	case float32:
-		return data.FieldTypeFloat32
-	case *float32:
-		return data.FieldTypeNullableFloat32
-	case float64:
-		return data.FieldTypeFloat64
-	case *float64:
-		return data.FieldTypeNullableFloat64
-
-	// others
-	case string:
-		return data.FieldTypeString
-	case *string:
-		return data.FieldTypeNullableString
-	case bool:
-		return data.FieldTypeBool
-	case *bool:
-		return data.FieldTypeNullableBool
-	case time.Time:
-		return data.FieldTypeTime
-	case *time.Time:
-		return data.FieldTypeNullableTime
+func asJsonString(value interface{}) string {
 
-	default:
-		panic("unsupported type")
+	json, err := bson.MarshalExtJSON(value, false, false)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
 	}
+	return string(json)
+}
+
+// Options control how BSON values are converted into field values.
+type Options struct {
+	// Decimal128 selects how Decimal128 values are converted, defaults to
+	// Decimal128String.
+	Decimal128 Decimal128Mode
+
+	// ExactDecimalFields lists the fields that keep Decimal128 values as
+	// exact strings when Decimal128 is Decimal128Float.
+	ExactDecimalFields []string
+
+	// ObjectIdTimeFields lists the ObjectId fields that are rendered as hex
+	// strings with a companion time field holding the creation time of the
+	// id. The companion field is named after the field with the
+	// ObjectIdTimeSuffix.
+	ObjectIdTimeFields []string
+
+	// UUIDRepresentation selects the byte order of legacy subtype 3 UUIDs,
+	// defaults to UUIDPythonLegacy. Subtype 3 and 4 binary values are
+	// rendered as canonical UUID strings.
+	UUIDRepresentation UUIDRepresentation
+
+	// DetectGeoJSON converts GeoJSON Point values in any field into numeric
+	// latitude and longitude fields named after the field with the
+	// LatitudeSuffix and LongitudeSuffix. Other GeoJSON geometries are
+	// rendered as GeoJSON strings.
+	DetectGeoJSON bool
+
+	// GeoJSONFields lists the fields whose GeoJSON Point values are converted
+	// when DetectGeoJSON is false.
+	GeoJSONFields []string
+
+	// DocumentField, when set, adds a JSON field with this name holding the
+	// whole document to each row.
+	DocumentField string
+
+	// DocumentJSON selects how the document field is rendered, defaults to
+	// JSONRelaxed.
+	DocumentJSON JSONMode
+
+	// Schema declares fields that are always returned, in order before any
+	// other fields, with their values cast to the declared types.
+	Schema []Column
+
+	// DerivedSchema lists the fields expected from a query, returned when
+	// there are no records and no Schema, so an empty result keeps its
+	// columns.
+	DerivedSchema []Column
+
+	// Columns lists the fields returned first, in order, optionally renamed.
+	Columns []OutputColumn
+
+	// ExcludeColumns lists fields that are not returned.
+	ExcludeColumns []string
+
+	// IgnoredFields lists document fields that are not read, such as the
+	// arrays returned as child frames, so they add nothing to the size of
+	// the fields.
+	IgnoredFields []string
+
+	// OnlyColumns returns only the fields listed in Columns.
+	OnlyColumns bool
+
+	// ColumnOrder orders the fields that are not listed in Columns,
+	// defaults to OrderFirstSeen.
+	ColumnOrder ColumnOrder
+
+	// FieldConfig sets the config of fields by name, replacing the
+	// properties read from meta documents.
+	FieldConfig map[string]FieldConfig
 }
 
+// ObjectIdTimeSuffix is appended to the name of an ObjectId field to name its
+// companion time field.
+const ObjectIdTimeSuffix = ".time"
+
 type FieldBuilder struct {
-	recordCount int
-	fields      []*field
-	index       map[string]*field
+	recordCount   int
+	fields        []*field
+	index         map[string]*field
+	options       Options
+	exactDecimals map[string]bool
+	objectIdTimes map[string]bool
+	geoJSONFields map[string]bool
+	ignored       map[string]bool
+	metaConfigs   map[string]FieldConfig
 }
 
 func NewFieldBuilder(capacity int) *FieldBuilder {
-	return &FieldBuilder{
-		fields: make([]*field, 0, capacity),
-		index:  make(map[string]*field),
+	return NewFieldBuilderWithOptions(capacity, Options{})
+}
+
+func NewFieldBuilderWithOptions(capacity int, options Options) *FieldBuilder {
+
+	exactDecimals := make(map[string]bool)
+	for _, name := range options.ExactDecimalFields {
+		exactDecimals[name] = true
+	}
+
+	objectIdTimes := make(map[string]bool)
+	for _, name := range options.ObjectIdTimeFields {
+		objectIdTimes[name] = true
+	}
+
+	geoJSONFields := make(map[string]bool)
+	for _, name := range options.GeoJSONFields {
+		geoJSONFields[name] = true
 	}
+
+	ignored := make(map[string]bool)
+	for _, name := range options.IgnoredFields {
+		ignored[name] = true
+	}
+
+	fb := &FieldBuilder{
+		fields:        make([]*field, 0, capacity),
+		index:         make(map[string]*field),
+		options:       options,
+		exactDecimals: exactDecimals,
+		objectIdTimes: objectIdTimes,
+		geoJSONFields: geoJSONFields,
+		ignored:       ignored,
+		metaConfigs:   make(map[string]FieldConfig),
+	}
+
+	for _, column := range options.Schema {
+		fb.field(column.Name).declare(column)
+	}
+	return fb
 }
 
 func (fb *FieldBuilder) ProcessRecord(record primitive.D) {
 
+	if isMetaDocument(record) {
+		fb.processMeta(record)
+		return
+	}
+
 	for _, e := range record {
-		field := fb.field(e.Key)
-		field.expandTo(fb.recordCount)
-		field.append(e.Value)
+		if e.Key == fb.options.DocumentField || fb.ignored[e.Key] {
+			continue
+		}
+
+		fb.appendValue(e.Key, e.Value)
+	}
+
+	if fb.options.DocumentField != "" {
+		fb.currentField(fb.options.DocumentField).append(documentJSON(record, fb.options.DocumentJSON))
 	}
 	fb.recordCount++
 }
 
-func (fb *FieldBuilder) BuildFields() []*data.Field {
+func (fb *FieldBuilder) appendValue(name string, value interface{}) {
+
+	if field := fb.index[name]; field != nil && field.declared != nil {
+		fb.currentField(name).appendCast(value)
+		return
+	}
+
+	switch value := value.(type) {
+
+	case primitive.Decimal128:
+		if fb.decimalAsFloat(name) {
+			fb.currentField(name).appendDecimal(value)
+			return
+		}
+
+	case primitive.ObjectID:
+		if fb.objectIdTimes[name] {
+			fb.currentField(name).append(value.Hex())
+			fb.appendValue(name+ObjectIdTimeSuffix, value.Timestamp())
+			return
+		}
+
+	case primitive.Binary:
+		uuid, ok := uuidString(value, fb.options.UUIDRepresentation)
+		if ok {
+			fb.currentField(name).append(uuid)
+			return
+		}
+
+	case primitive.D:
+		if fb.geoJSON(name) {
+			latitude, longitude, ok := geoJSONPoint(value)
+			if ok {
+				fb.appendValue(name+LatitudeSuffix, latitude)
+				fb.appendValue(name+LongitudeSuffix, longitude)
+				return
+			}
+		}
+	}
+	fb.currentField(name).append(value)
+}
 
-	fields := make([]*data.Field, 0, fb.recordCount)
+// Size returns an estimate of the memory used by the values of the fields in
+// bytes.
+func (fb *FieldBuilder) Size() int {
+
+	size := 0
+	for _, field := range fb.fields {
+		size += field.values.size()
+	}
+	return size
+}
+
+// Notices returns warnings about values that could not be converted
+// faithfully.
+func (fb *FieldBuilder) Notices() []data.Notice {
+
+	notices := make([]data.Notice, 0)
 	for _, field := range fb.fields {
-		field.expandTo(fb.recordCount)
-		fields = append(fields, field.build())
+		notices = append(notices, field.notices()...)
 	}
-	return fields
+	return notices
+}
+
+func (fb *FieldBuilder) decimalAsFloat(name string) bool {
+	return fb.options.Decimal128 == Decimal128Float && !fb.exactDecimals[name]
+}
+
+func (fb *FieldBuilder) geoJSON(name string) bool {
+	return fb.options.DetectGeoJSON || fb.geoJSONFields[name]
+}
+
+func (fb *FieldBuilder) BuildFields() []*data.Field {
+
+	var fields []*data.Field
+	if fb.recordCount == 0 && len(fb.fields) == 0 {
+		fields = emptyFields(fb.options.DerivedSchema)
+	} else {
+		fields = make([]*data.Field, 0, len(fb.fields))
+		for _, field := range fb.fields {
+			field.expandTo(fb.recordCount)
+			fields = append(fields, field.build())
+		}
+	}
+
+	fb.applyConfigs(fields)
+	return fb.selectFields(fields)
+}
+
+// currentField returns the named field padded to the current record.
+func (fb *FieldBuilder) currentField(name string) *field {
+
+	field := fb.field(name)
+	field.expandTo(fb.recordCount)
+	return field
 }
 
 func (fb *FieldBuilder) field(name string) *field {
@@ -0,0 +1,37 @@
+package field
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// LatitudeSuffix is appended to the name of a GeoJSON field to name its
+	// latitude field.
+	LatitudeSuffix = ".latitude"
+
+	// LongitudeSuffix is appended to the name of a GeoJSON field to name its
+	// longitude field.
+	LongitudeSuffix = ".longitude"
+)
+
+// geoJSONPoint returns the latitude and longitude of a GeoJSON Point, whose
+// coordinates are in longitude, latitude order.
+func geoJSONPoint(value primitive.D) (float64, float64, bool) {
+
+	values := value.Map()
+	if values["type"] != "Point" {
+		return 0, 0, false
+	}
+
+	coordinates, ok := values["coordinates"].(primitive.A)
+	if !ok || len(coordinates) < 2 {
+		return 0, 0, false
+	}
+
+	longitude, ok1 := castFloat(coordinates[0])
+	latitude, ok2 := castFloat(coordinates[1])
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	return latitude, longitude, true
+}
@@ -0,0 +1,45 @@
+package field
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGeoJSONPoint(t *testing.T) {
+
+	decimal128, _ := primitive.ParseDecimal128("51.5")
+
+	var tests = []struct {
+		value primitive.D
+		want1 float64
+		want2 float64
+		want3 bool
+	}{
+		//Point
+		{primitive.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: primitive.A{-0.12, 51.5}}},
+			51.5, -0.12, true},
+
+		//Point with integer and decimal coordinates
+		{primitive.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: primitive.A{int32(3), decimal128}}},
+			51.5, 3, true},
+
+		//Not a Point
+		{primitive.D{{Key: "type", Value: "LineString"}, {Key: "coordinates", Value: primitive.A{primitive.A{1.0, 2.0}}}},
+			0, 0, false},
+
+		//Missing coordinates
+		{primitive.D{{Key: "type", Value: "Point"}},
+			0, 0, false},
+
+		//Invalid coordinates
+		{primitive.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: primitive.A{"a", 1.0}}},
+			0, 0, false},
+	}
+
+	for _, test := range tests {
+		if got1, got2, got3 := geoJSONPoint(test.value); got1 != test.want1 || got2 != test.want2 || got3 != test.want3 {
+			t.Errorf("geoJSONPoint(%v) = (%v,%v,%v)", test.value, got1, got2, got3)
+		}
+	}
+}
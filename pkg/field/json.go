@@ -0,0 +1,124 @@
+package field
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JSONMode string
+
+const (
+	// JSONRelaxed renders documents as relaxed Extended JSON.
+	JSONRelaxed JSONMode = "relaxed"
+
+	// JSONCanonical renders documents as canonical Extended JSON.
+	JSONCanonical JSONMode = "canonical"
+
+	// JSONShell renders special types as the strings used by the MongoDB
+	// shell e.g. ObjectId("...") and ISODate("...").
+	JSONShell JSONMode = "shell"
+)
+
+func (m JSONMode) IsValid() bool {
+	return m == "" || m == JSONRelaxed || m == JSONCanonical || m == JSONShell
+}
+
+func documentJSON(document primitive.D, mode JSONMode) json.RawMessage {
+
+	if mode == JSONShell {
+		var buffer bytes.Buffer
+		writeShellJSON(&buffer, document)
+		return buffer.Bytes()
+	}
+
+	result, err := bson.MarshalExtJSON(document, mode == JSONCanonical, false)
+	if err != nil {
+		result, _ = json.Marshal(err.Error())
+	}
+	return result
+}
+
+func writeShellJSON(buffer *bytes.Buffer, value interface{}) {
+
+	switch value := value.(type) {
+
+	case primitive.D:
+		buffer.WriteByte('{')
+		for i, e := range value {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeJSON(buffer, e.Key)
+			buffer.WriteByte(':')
+			writeShellJSON(buffer, e.Value)
+		}
+		buffer.WriteByte('}')
+
+	case primitive.M:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		document := make(primitive.D, 0, len(value))
+		for _, key := range keys {
+			document = append(document, primitive.E{Key: key, Value: value[key]})
+		}
+		writeShellJSON(buffer, document)
+
+	case primitive.A:
+		buffer.WriteByte('[')
+		for i, v := range value {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeShellJSON(buffer, v)
+		}
+		buffer.WriteByte(']')
+
+	case nil, primitive.Null:
+		buffer.WriteString("null")
+
+	case primitive.DateTime:
+		writeJSON(buffer, fmt.Sprintf("ISODate(%q)", value.Time().UTC().Format("2006-01-02T15:04:05.000Z")))
+
+	case primitive.Decimal128:
+		writeJSON(buffer, fmt.Sprintf("NumberDecimal(%q)", value.String()))
+
+	case int64:
+		writeJSON(buffer, fmt.Sprintf("NumberLong(%d)", value))
+
+	case primitive.Timestamp:
+		writeJSON(buffer, fmt.Sprintf("Timestamp(%d, %d)", value.T, value.I))
+
+	case primitive.Binary:
+		writeJSON(buffer, fmt.Sprintf(`BinData(%d, %q)`, value.Subtype, base64.StdEncoding.EncodeToString(value.Data)))
+
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			writeJSON(buffer, fmt.Sprintf("%v", value))
+		} else {
+			writeJSON(buffer, value)
+		}
+
+	case bool, int32, string:
+		writeJSON(buffer, value)
+
+	default:
+		writeJSON(buffer, StringValue(value))
+	}
+}
+
+func writeJSON(buffer *bytes.Buffer, value interface{}) {
+
+	result, _ := json.Marshal(value)
+	buffer.Write(result)
+}
@@ -0,0 +1,58 @@
+package field
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDocumentJSON(t *testing.T) {
+
+	objectId, _ := primitive.ObjectIDFromHex("609844760000000000000000")
+	decimal128, _ := primitive.ParseDecimal128("1.5")
+	datetime := primitive.NewDateTimeFromTime(time.Unix(1620586358, 0))
+
+	var tests = []struct {
+		document primitive.D
+		mode     JSONMode
+		want     string
+	}{
+		//Relaxed by default
+		{primitive.D{{Key: "_id", Value: objectId}, {Key: "n", Value: int64(5)}}, "",
+			`{"_id":{"$oid":"609844760000000000000000"},"n":5}`},
+
+		//Relaxed
+		{primitive.D{{Key: "date", Value: datetime}}, JSONRelaxed,
+			`{"date":{"$date":"2021-05-09T18:52:38Z"}}`},
+
+		//Canonical
+		{primitive.D{{Key: "n", Value: int32(5)}, {Key: "d", Value: decimal128}}, JSONCanonical,
+			`{"n":{"$numberInt":"5"},"d":{"$numberDecimal":"1.5"}}`},
+
+		//Shell
+		{primitive.D{
+			{Key: "_id", Value: objectId},
+			{Key: "date", Value: datetime},
+			{Key: "d", Value: decimal128},
+			{Key: "n", Value: int64(5)},
+			{Key: "f", Value: math.Inf(1)},
+			{Key: "nested", Value: primitive.M{"b": primitive.Null{}, "a": primitive.A{int32(1), "x", true}}},
+			{Key: "re", Value: primitive.Regex{Pattern: ".+", Options: "i"}},
+		}, JSONShell,
+			`{"_id":"ObjectId(\"609844760000000000000000\")","date":"ISODate(\"2021-05-09T18:52:38.000Z\")",` +
+				`"d":"NumberDecimal(\"1.5\")","n":"NumberLong(5)","f":"+Inf","nested":{"a":[1,"x",true],"b":null},"re":"/.+/i"}`},
+	}
+
+	for _, test := range tests {
+		got := documentJSON(test.document, test.mode)
+		if string(got) != test.want {
+			t.Errorf("documentJSON(%v, %q) = %s", test.document, test.mode, got)
+		}
+		if !json.Valid(got) {
+			t.Errorf("documentJSON(%v, %q) is not valid JSON", test.document, test.mode)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package field
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+var errMalformedRecord = errors.New("malformed BSON document")
+
+// ProcessRawRecord appends the elements of a BSON document to the fields.
+// Scalar values are read straight from the document, avoiding the primitive.D
+// tree built by ProcessRecord, other values are decoded as ProcessRecord would
+// see them.
+func (fb *FieldBuilder) ProcessRawRecord(record bson.Raw) error {
+
+	// the document field renders the whole record
+	if fb.options.DocumentField != "" || isRawMetaDocument(record) {
+		var rec primitive.D
+		err := bson.Unmarshal(record, &rec)
+		if err != nil {
+			return err
+		}
+		fb.ProcessRecord(rec)
+		return nil
+	}
+
+	length, rem, ok := bsoncore.ReadLength(record)
+	if !ok || length < 5 || int(length) > len(record) {
+		return errMalformedRecord
+	}
+
+	rem = rem[:length-5]
+	for len(rem) > 0 {
+
+		var element bsoncore.Element
+		element, rem, ok = bsoncore.ReadElement(rem)
+		if !ok {
+			return errMalformedRecord
+		}
+
+		if fb.ignored[string(element.KeyBytes())] {
+			continue
+		}
+
+		err := fb.appendRawValue(element.KeyBytes(), element.Value())
+		if err != nil {
+			return err
+		}
+	}
+	fb.recordCount++
+	return nil
+}
+
+func (fb *FieldBuilder) appendRawValue(key []byte, value bsoncore.Value) error {
+
+	// declared fields cast the decoded value
+	if field := fb.index[string(key)]; field != nil && field.declared != nil {
+		return fb.appendDecodedValue(key, value)
+	}
+
+	ok := true
+	switch value.Type {
+
+	case bsontype.Null:
+		fb.rawField(key).appendNull()
+
+	case bsontype.Int32:
+		var v int32
+		if v, ok = value.Int32OK(); ok {
+			fb.rawField(key).appendInt32(v)
+		}
+
+	case bsontype.Int64:
+		var v int64
+		if v, ok = value.Int64OK(); ok {
+			fb.rawField(key).appendInt64(v)
+		}
+
+	case bsontype.Double:
+		var v float64
+		if v, ok = value.DoubleOK(); ok {
+			fb.rawField(key).appendFloat64(v)
+		}
+
+	case bsontype.Boolean:
+		var v bool
+		if v, ok = value.BooleanOK(); ok {
+			fb.rawField(key).appendBool(v)
+		}
+
+	case bsontype.DateTime:
+		var v int64
+		if v, ok = value.DateTimeOK(); ok {
+			fb.rawField(key).appendTime(primitive.DateTime(v).Time())
+		}
+
+	case bsontype.String:
+		var v string
+		if v, ok = value.StringValueOK(); ok {
+			fb.rawField(key).appendString(v)
+		}
+
+	default:
+		return fb.appendDecodedValue(key, value)
+	}
+
+	if !ok {
+		return errMalformedRecord
+	}
+	return nil
+}
+
+// appendDecodedValue appends a value decoded as ProcessRecord sees it.
+func (fb *FieldBuilder) appendDecodedValue(key []byte, value bsoncore.Value) error {
+
+	var v interface{}
+	err := bson.RawValue{Type: value.Type, Value: value.Data}.Unmarshal(&v)
+	if err != nil {
+		return err
+	}
+	fb.appendValue(string(key), v)
+	return nil
+}
+
+func isRawMetaDocument(record bson.Raw) bool {
+
+	element, err := bsoncore.Document(record).IndexErr(0)
+	if err != nil || string(element.KeyBytes()) != "_id" {
+		return false
+	}
+	id, ok := element.Value().StringValueOK()
+	return ok && id == MetaDocumentId
+}
+
+// rawField returns the field of the key padded to the current record without
+// allocating the name of existing fields.
+func (fb *FieldBuilder) rawField(key []byte) *field {
+
+	result := fb.index[string(key)]
+	if result == nil {
+		result = fb.field(string(key))
+	}
+	result.expandTo(fb.recordCount)
+	return result
+}
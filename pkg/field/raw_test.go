@@ -0,0 +1,161 @@
+package field
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFieldBuilderProcessRawRecord(t *testing.T) {
+
+	id := primitive.NewObjectIDFromTimestamp(time.Unix(1620586358, 0))
+	decimal, _ := primitive.ParseDecimal128("1.5")
+	point := primitive.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: primitive.A{-0.12, 51.5}}}
+
+	records := []primitive.D{
+		{
+			{Key: "_id", Value: id},
+			{Key: "time", Value: primitive.NewDateTimeFromTime(time.Unix(1620586358, 0))},
+			{Key: "int32", Value: int32(1)},
+			{Key: "int64", Value: int64(2)},
+			{Key: "double", Value: 3.5},
+			{Key: "bool", Value: true},
+			{Key: "string", Value: "a"},
+			{Key: "null", Value: nil},
+			{Key: "decimal", Value: decimal},
+			{Key: "loc", Value: point},
+			{Key: "array", Value: primitive.A{int32(1), "b"}},
+			{Key: "doc", Value: primitive.D{{Key: "a", Value: int32(1)}}},
+		},
+		{
+			{Key: "_id", Value: MetaDocumentId},
+			{Key: "double", Value: primitive.D{{Key: "unit", Value: "percent"}}},
+		},
+		{
+			{Key: "int32", Value: int64(3)},
+			{Key: "string", Value: int32(4)},
+			{Key: "extra", Value: "c"},
+		},
+		{},
+	}
+
+	var tests = []Options{
+		//Default options
+		{},
+
+		//Conversions
+		{Decimal128: Decimal128Float, ObjectIdTimeFields: []string{"_id"}, DetectGeoJSON: true},
+
+		//Document field
+		{DocumentField: "document"},
+
+		//Ignored fields
+		{IgnoredFields: []string{"array", "doc"}},
+
+		//Declared fields
+		{Schema: []Column{{Name: "string", Type: ColumnInt}, {Name: "double"}, {Name: "missing", Type: ColumnTime}}},
+	}
+
+	for _, options := range tests {
+
+		want := NewFieldBuilderWithOptions(5, options)
+		got := NewFieldBuilderWithOptions(5, options)
+		for _, record := range records {
+			raw, _ := bson.Marshal(record)
+			want.ProcessRecord(record)
+			if err := got.ProcessRawRecord(raw); err != nil {
+				t.Fatalf("%v fieldBuilder.ProcessRawRecord() error = %v", options, err)
+			}
+		}
+
+		if fields := got.BuildFields(); !reflect.DeepEqual(fields, want.BuildFields()) {
+			t.Errorf("%v fieldBuilder.BuildFields() = %v", options, fields)
+		}
+	}
+}
+
+func TestFieldBuilderIgnoredFields(t *testing.T) {
+
+	raw, _ := bson.Marshal(primitive.D{{Key: "n", Value: int32(1)}, {Key: "tags", Value: primitive.A{"a", "b"}}})
+
+	want := NewFieldBuilder(5)
+	want.ProcessRecord(primitive.D{{Key: "n", Value: int32(1)}})
+
+	// ignored fields are neither returned nor counted in the size
+	got := NewFieldBuilderWithOptions(5, Options{IgnoredFields: []string{"tags"}})
+	if err := got.ProcessRawRecord(raw); err != nil || got.Size() != want.Size() {
+		t.Errorf("fieldBuilder.Size() = %d, %v", got.Size(), err)
+	}
+	if fields := got.BuildFields(); !reflect.DeepEqual(fields, want.BuildFields()) {
+		t.Errorf("fieldBuilder.BuildFields() = %v", fields)
+	}
+}
+
+func TestFieldBuilderProcessRawRecordMalformed(t *testing.T) {
+
+	raw, _ := bson.Marshal(primitive.D{{Key: "a", Value: "b"}})
+
+	var tests = []bson.Raw{
+		//Truncated length
+		raw[:3],
+
+		//Truncated document
+		raw[:len(raw)-4],
+
+		//Truncated element
+		append(bson.Raw{0x0a, 0, 0, 0}, raw[4:10]...),
+	}
+
+	for _, test := range tests {
+		if err := NewFieldBuilder(5).ProcessRawRecord(test); err != errMalformedRecord {
+			t.Errorf("%v fieldBuilder.ProcessRawRecord() error = %v", test, err)
+		}
+	}
+}
+
+func benchmarkRawRecords(count int) []bson.Raw {
+
+	records := benchmarkRecords(count)
+	raws := make([]bson.Raw, len(records))
+	for i, record := range records {
+		raws[i], _ = bson.Marshal(record)
+	}
+	return raws
+}
+
+// BenchmarkFieldBuilderDecode measures decoding each document into a
+// primitive.D as the DataHandler path does.
+func BenchmarkFieldBuilderDecode(b *testing.B) {
+
+	raws := benchmarkRawRecords(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		fieldBuilder := NewFieldBuilder(5)
+		for _, raw := range raws {
+			var record primitive.D
+			bson.Unmarshal(raw, &record)
+			fieldBuilder.ProcessRecord(record)
+		}
+		fieldBuilder.BuildFields()
+	}
+}
+
+func BenchmarkFieldBuilderRaw(b *testing.B) {
+
+	raws := benchmarkRawRecords(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		fieldBuilder := NewFieldBuilder(5)
+		for _, raw := range raws {
+			fieldBuilder.ProcessRawRecord(raw)
+		}
+		fieldBuilder.BuildFields()
+	}
+}
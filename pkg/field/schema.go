@@ -0,0 +1,221 @@
+package field
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ColumnType string
+
+const (
+	ColumnString ColumnType = "string"
+	ColumnInt    ColumnType = "int"
+	ColumnFloat  ColumnType = "float"
+	ColumnBool   ColumnType = "bool"
+	ColumnTime   ColumnType = "time"
+)
+
+func (t ColumnType) IsValid() bool {
+	return t == "" || t == ColumnString || t == ColumnInt || t == ColumnFloat || t == ColumnBool || t == ColumnTime
+}
+
+// fieldType returns the type of a field declared with the column type,
+// defaulting to string.
+func (t ColumnType) fieldType() data.FieldType {
+
+	switch t {
+	case ColumnInt:
+		return data.FieldTypeNullableInt64
+	case ColumnFloat:
+		return data.FieldTypeNullableFloat64
+	case ColumnBool:
+		return data.FieldTypeNullableBool
+	case ColumnTime:
+		return data.FieldTypeNullableTime
+	default:
+		return data.FieldTypeNullableString
+	}
+}
+
+func (t ColumnType) kind() valueKind {
+
+	switch t {
+	case ColumnInt:
+		return kindInt64
+	case ColumnFloat:
+		return kindFloat64
+	case ColumnBool:
+		return kindBool
+	case ColumnTime:
+		return kindTime
+	default:
+		return kindString
+	}
+}
+
+type EpochUnit string
+
+// epochUnits are the nanoseconds in each unit of epoch times.
+var epochUnits = map[EpochUnit]int64{
+	"":   1e6,
+	"s":  1e9,
+	"ms": 1e6,
+	"us": 1e3,
+	"ns": 1,
+}
+
+func (u EpochUnit) IsValid() bool {
+	_, ok := epochUnits[u]
+	return ok
+}
+
+// Column declares a field of the result of a query.
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type"`
+
+	// Layout is the layout of time strings, defaults to RFC 3339.
+	Layout string `json:"layout"`
+
+	// Unit is the unit of epoch times, defaults to milliseconds. Strings
+	// holding epoch times are parsed when the unit is set without a layout.
+	Unit EpochUnit `json:"unit"`
+}
+
+// emptyFields returns a field without values for each column of the schema.
+func emptyFields(schema []Column) []*data.Field {
+
+	fields := make([]*data.Field, len(schema))
+	for i, column := range schema {
+		fields[i] = data.NewFieldFromFieldType(column.Type.fieldType(), 0)
+		fields[i].Name = column.Name
+	}
+	return fields
+}
+
+func castInt(value interface{}) (int64, bool) {
+
+	switch value := value.(type) {
+	case int32:
+		return int64(value), true
+	case int64:
+		return value, true
+	case float64:
+		return floatToInt(value)
+	case primitive.Decimal128:
+		result, _ := decimalToFloat(value)
+		return floatToInt(result)
+	case string:
+		result, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		return result, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// floatToInt converts whole numbers to int64.
+func floatToInt(value float64) (int64, bool) {
+
+	if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(value), true
+}
+
+// FloatValue converts a numeric BSON value to the nearest float64, the
+// second result is false for other values.
+func FloatValue(value interface{}) (float64, bool) {
+
+	switch value := value.(type) {
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	case primitive.Decimal128:
+		result, _ := decimalToFloat(value)
+		return result, true
+	default:
+		return 0, false
+	}
+}
+
+func castFloat(value interface{}) (float64, bool) {
+
+	if value, ok := value.(string); ok {
+		result, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return result, err == nil
+	}
+	return FloatValue(value)
+}
+
+func castBool(value interface{}) (bool, bool) {
+
+	switch value := value.(type) {
+	case bool:
+		return value, true
+	case int32:
+		return value != 0, true
+	case int64:
+		return value != 0, true
+	case float64:
+		return value != 0, true
+	case string:
+		result, err := strconv.ParseBool(strings.TrimSpace(value))
+		return result, err == nil
+	default:
+		return false, false
+	}
+}
+
+func castTime(value interface{}, column Column) (time.Time, bool) {
+
+	unit := epochUnits[column.Unit]
+	switch value := value.(type) {
+	case primitive.DateTime:
+		return value.Time(), true
+	case time.Time:
+		return value, true
+	case primitive.Timestamp:
+		return time.Unix(int64(value.T), 0), true
+	case primitive.ObjectID:
+		return value.Timestamp(), true
+	case int32:
+		return epochTime(int64(value), unit), true
+	case int64:
+		return epochTime(value, unit), true
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return time.Time{}, false
+		}
+		return time.Unix(0, int64(value*float64(unit))), true
+	case string:
+		value = strings.TrimSpace(value)
+		if column.Layout == "" && column.Unit != "" {
+			epoch, err := strconv.ParseInt(value, 10, 64)
+			return epochTime(epoch, unit), err == nil
+		}
+
+		layout := column.Layout
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		result, err := time.Parse(layout, value)
+		return result, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+// epochTime returns the time of an epoch in units of the given nanoseconds.
+func epochTime(epoch int64, unit int64) time.Time {
+
+	perSecond := int64(time.Second) / unit
+	return time.Unix(epoch/perSecond, epoch%perSecond*unit)
+}
@@ -0,0 +1,249 @@
+package field
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFieldBuilderDerivedSchema(t *testing.T) {
+
+	schema := []Column{
+		{Name: "time", Type: ColumnTime},
+		{Name: "count", Type: ColumnInt},
+		{Name: "value", Type: ColumnFloat},
+		{Name: "up", Type: ColumnBool},
+		{Name: "host", Type: ColumnString},
+		{Name: "other"},
+	}
+
+	var tests = []struct {
+		records []primitive.D
+		want    []*data.Field
+	}{
+		//Empty result
+		{[]primitive.D{},
+			[]*data.Field{
+				data.NewField("time", nil, []*time.Time{}),
+				data.NewField("count", nil, []*int64{}),
+				data.NewField("value", nil, []*float64{}),
+				data.NewField("up", nil, []*bool{}),
+				data.NewField("host", nil, []*string{}),
+				data.NewField("other", nil, []*string{}),
+			}},
+
+		//Records are not changed
+		{[]primitive.D{{{Key: "host", Value: "a"}}},
+			[]*data.Field{
+				data.NewField("host", nil, []string{"a"}),
+			}},
+	}
+
+	for _, test := range tests {
+
+		fieldBuilder := NewFieldBuilderWithOptions(5, Options{DerivedSchema: schema})
+		for _, record := range test.records {
+			fieldBuilder.ProcessRecord(record)
+		}
+
+		if got := fieldBuilder.BuildFields(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v fieldBuilder.BuildFields() = %v", test.records, got)
+		}
+	}
+}
+
+func TestFieldBuilderSchema(t *testing.T) {
+
+	schema := []Column{
+		{Name: "time", Type: ColumnTime},
+		{Name: "count", Type: ColumnInt},
+		{Name: "value", Type: ColumnFloat},
+		{Name: "up", Type: ColumnBool},
+		{Name: "host"},
+	}
+
+	t1 := time.Date(2021, 5, 9, 18, 52, 38, 0, time.UTC)
+	timePtr := func(value time.Time) *time.Time { return &value }
+	int64Ptr := func(value int64) *int64 { return &value }
+	boolPtr := func(value bool) *bool { return &value }
+
+	var tests = []struct {
+		records []primitive.D
+		want    []*data.Field
+		notices []data.Notice
+	}{
+		//Empty result
+		{[]primitive.D{},
+			[]*data.Field{
+				data.NewField("time", nil, []*time.Time{}),
+				data.NewField("count", nil, []*int64{}),
+				data.NewField("value", nil, []*float64{}),
+				data.NewField("up", nil, []*bool{}),
+				data.NewField("host", nil, []*string{}),
+			},
+			[]data.Notice{}},
+
+		//Values are cast and undeclared fields follow the declared fields
+		{[]primitive.D{
+			{{Key: "extra", Value: int32(1)}, {Key: "time", Value: "2021-05-09T18:52:38Z"}, {Key: "count", Value: "12"},
+				{Key: "value", Value: "1.5"}, {Key: "up", Value: "true"}, {Key: "host", Value: int32(7)}},
+			{{Key: "time", Value: primitive.NewDateTimeFromTime(t1)}, {Key: "count", Value: 3.0},
+				{Key: "value", Value: int64(2)}, {Key: "up", Value: int32(0)}, {Key: "host", Value: nil}},
+		},
+			[]*data.Field{
+				data.NewField("time", nil, []*time.Time{timePtr(t1), timePtr(t1.Local())}),
+				data.NewField("count", nil, []*int64{int64Ptr(12), int64Ptr(3)}),
+				data.NewField("value", nil, []*float64{float64Ptr(1.5), float64Ptr(2)}),
+				data.NewField("up", nil, []*bool{boolPtr(true), boolPtr(false)}),
+				data.NewField("host", nil, []*string{stringPtr("7"), nil}),
+				data.NewField("extra", nil, []*int32{int32Ptr(1), nil}),
+			},
+			[]data.Notice{}},
+
+		//Cast failures
+		{[]primitive.D{
+			{{Key: "time", Value: "yesterday"}, {Key: "count", Value: 1.5}, {Key: "value", Value: "x"}, {Key: "up", Value: "maybe"}},
+			{{Key: "count", Value: "x"}},
+		},
+			[]*data.Field{
+				data.NewField("time", nil, []*time.Time{nil, nil}),
+				data.NewField("count", nil, []*int64{nil, nil}),
+				data.NewField("value", nil, []*float64{nil, nil}),
+				data.NewField("up", nil, []*bool{nil, nil}),
+				data.NewField("host", nil, []*string{nil, nil}),
+			},
+			[]data.Notice{
+				{Severity: data.NoticeSeverityWarning, Text: `1 value(s) in field "time" could not be converted to time and were replaced by null`},
+				{Severity: data.NoticeSeverityWarning, Text: `2 value(s) in field "count" could not be converted to int and were replaced by null`},
+				{Severity: data.NoticeSeverityWarning, Text: `1 value(s) in field "value" could not be converted to float and were replaced by null`},
+				{Severity: data.NoticeSeverityWarning, Text: `1 value(s) in field "up" could not be converted to bool and were replaced by null`},
+			}},
+	}
+
+	for _, test := range tests {
+
+		fieldBuilder := NewFieldBuilderWithOptions(5, Options{Schema: schema})
+		for _, record := range test.records {
+			fieldBuilder.ProcessRecord(record)
+		}
+
+		if got := fieldBuilder.BuildFields(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v fieldBuilder.BuildFields() = %v", test.records, got)
+		}
+
+		if got := fieldBuilder.Notices(); !reflect.DeepEqual(got, test.notices) {
+			t.Errorf("%v fieldBuilder.Notices() = %v", test.records, got)
+		}
+	}
+}
+
+func int32Ptr(value int32) *int32 {
+	return &value
+}
+
+func TestCastTime(t *testing.T) {
+
+	t1 := time.Date(2021, 5, 9, 18, 52, 38, 0, time.UTC)
+	t2 := time.Date(2021, 5, 9, 18, 52, 38, 123456789, time.UTC)
+
+	var tests = []struct {
+		value  interface{}
+		column Column
+		want1  time.Time
+		want2  bool
+	}{
+		//RFC 3339
+		{"2021-05-09T18:52:38.123456789Z", Column{}, t2, true},
+
+		//Custom layout
+		{"2021-05-09 18:52:38", Column{Layout: "2006-01-02 15:04:05"}, t1, true},
+
+		//Epoch milliseconds by default
+		{int64(1620586358123), Column{}, time.Unix(1620586358, 123000000), true},
+
+		//Epoch seconds
+		{int32(1620586358), Column{Unit: "s"}, time.Unix(1620586358, 0), true},
+
+		//Epoch fractional seconds
+		{1620586358.5, Column{Unit: "s"}, time.Unix(1620586358, 500000000), true},
+
+		//Epoch microseconds
+		{int64(1620586358123456), Column{Unit: "us"}, time.Unix(1620586358, 123456000), true},
+
+		//Epoch nanoseconds
+		{int64(1620586358123456789), Column{Unit: "ns"}, time.Unix(1620586358, 123456789), true},
+
+		//Epoch string
+		{"1620586358", Column{Unit: "s"}, time.Unix(1620586358, 0), true},
+
+		//Not a time
+		{"wibble", Column{}, time.Time{}, false},
+
+		//Not finite
+		{math.NaN(), Column{}, time.Time{}, false},
+
+		//Boolean
+		{true, Column{}, time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		if got1, got2 := castTime(test.value, test.column); !got1.Equal(test.want1) || got2 != test.want2 {
+			t.Errorf("castTime(%v, %v) = (%v, %v)", test.value, test.column, got1, got2)
+		}
+	}
+}
+
+func TestCastNumbers(t *testing.T) {
+
+	decimal, _ := primitive.ParseDecimal128("100")
+
+	var tests = []struct {
+		value     interface{}
+		wantInt   int64
+		wantInt2  bool
+		wantFloat float64
+		wantFlt2  bool
+	}{
+		//Integers
+		{int32(1), 1, true, 1, true},
+
+		//Whole float
+		{2.0, 2, true, 2, true},
+
+		//Fractional float
+		{2.5, 0, false, 2.5, true},
+
+		//Out of range float
+		{1e19, 0, false, 1e19, true},
+
+		//Decimal
+		{decimal, 100, true, 100, true},
+
+		//Strings
+		{" 42 ", 42, true, 42, true},
+
+		//Float string
+		{"4.5", 0, false, 4.5, true},
+
+		//Not a number
+		{"x", 0, false, 0, false},
+	}
+
+	for _, test := range tests {
+		if got1, got2 := castInt(test.value); got1 != test.wantInt || got2 != test.wantInt2 {
+			t.Errorf("castInt(%v) = (%v, %v)", test.value, got1, got2)
+		}
+		if got1, got2 := castFloat(test.value); got1 != test.wantFloat || got2 != test.wantFlt2 {
+			t.Errorf("castFloat(%v) = (%v, %v)", test.value, got1, got2)
+		}
+	}
+
+	// strings are cast but are not numeric values
+	if got1, got2 := FloatValue("42"); got2 {
+		t.Errorf("FloatValue(\"42\") = (%v, %v)", got1, got2)
+	}
+}
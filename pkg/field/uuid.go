@@ -0,0 +1,59 @@
+package field
+
+import (
+	"encoding/hex"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UUIDRepresentation selects the byte order of legacy subtype 3 UUIDs, which
+// depends on the driver that wrote them.
+type UUIDRepresentation string
+
+const (
+	// UUIDPythonLegacy reads the bytes in order, as the Python driver did.
+	UUIDPythonLegacy UUIDRepresentation = "pythonLegacy"
+
+	// UUIDJavaLegacy reverses the byte order of each 8 byte half.
+	UUIDJavaLegacy UUIDRepresentation = "javaLegacy"
+
+	// UUIDCSharpLegacy reverses the byte order of the first three groups.
+	UUIDCSharpLegacy UUIDRepresentation = "csharpLegacy"
+)
+
+func (r UUIDRepresentation) IsValid() bool {
+	return r == "" || r == UUIDPythonLegacy || r == UUIDJavaLegacy || r == UUIDCSharpLegacy
+}
+
+// uuidString returns the canonical string of a subtype 3 or 4 binary UUID.
+func uuidString(value primitive.Binary, representation UUIDRepresentation) (string, bool) {
+
+	if len(value.Data) != 16 || value.Subtype != 3 && value.Subtype != 4 {
+		return "", false
+	}
+
+	data := make([]byte, 16)
+	copy(data, value.Data)
+
+	if value.Subtype == 3 {
+		switch representation {
+		case UUIDJavaLegacy:
+			reverse(data[0:8])
+			reverse(data[8:16])
+		case UUIDCSharpLegacy:
+			reverse(data[0:4])
+			reverse(data[4:6])
+			reverse(data[6:8])
+		}
+	}
+
+	text := hex.EncodeToString(data)
+	return text[0:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:32], true
+}
+
+func reverse(data []byte) {
+
+	for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+		data[i], data[j] = data[j], data[i]
+	}
+}
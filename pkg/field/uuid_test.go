@@ -0,0 +1,58 @@
+package field
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUUIDString(t *testing.T) {
+
+	data, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+
+	var tests = []struct {
+		value          primitive.Binary
+		representation UUIDRepresentation
+		want1          string
+		want2          bool
+	}{
+		//Subtype 4
+		{primitive.Binary{Subtype: 4, Data: data}, UUIDJavaLegacy,
+			"00112233-4455-6677-8899-aabbccddeeff", true},
+
+		//Subtype 3 default
+		{primitive.Binary{Subtype: 3, Data: data}, "",
+			"00112233-4455-6677-8899-aabbccddeeff", true},
+
+		//Subtype 3 python legacy
+		{primitive.Binary{Subtype: 3, Data: data}, UUIDPythonLegacy,
+			"00112233-4455-6677-8899-aabbccddeeff", true},
+
+		//Subtype 3 java legacy
+		{primitive.Binary{Subtype: 3, Data: data}, UUIDJavaLegacy,
+			"77665544-3322-1100-ffee-ddccbbaa9988", true},
+
+		//Subtype 3 C# legacy
+		{primitive.Binary{Subtype: 3, Data: data}, UUIDCSharpLegacy,
+			"33221100-5544-7766-8899-aabbccddeeff", true},
+
+		//Not a UUID subtype
+		{primitive.Binary{Subtype: 0, Data: data}, "",
+			"", false},
+
+		//Wrong length
+		{primitive.Binary{Subtype: 4, Data: data[:8]}, "",
+			"", false},
+	}
+
+	for _, test := range tests {
+		if got1, got2 := uuidString(test.value, test.representation); got1 != test.want1 || got2 != test.want2 {
+			t.Errorf("uuidString(%v, %q) = (%v,%v)", test.value, test.representation, got1, got2)
+		}
+	}
+
+	if data[0] != 0x00 || data[15] != 0xff {
+		t.Errorf("uuidString modified the binary data %x", data)
+	}
+}
@@ -0,0 +1,119 @@
+package format
+
+import (
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ParentIdField is the field of a child frame holding the _id of the
+// document the row was embedded in.
+const ParentIdField = "_parentId"
+
+// ChildFramesBuilder converts named array fields of documents into frames of
+// their own, one row per embedded document, linked to the parent document by
+// the ParentIdField. Array elements that are not documents are ignored.
+type ChildFramesBuilder struct {
+	names    []string
+	children map[string]*field.FieldBuilder
+	hexIds   bool
+}
+
+// NewChildFramesBuilder returns a builder for the named array fields. The
+// values of the embedded documents are converted with the options of the
+// parent, without its field selection and schema.
+func NewChildFramesBuilder(names []string, options field.Options) *ChildFramesBuilder {
+
+	hexIds := false
+	for _, name := range options.ObjectIdTimeFields {
+		hexIds = hexIds || name == "_id"
+	}
+
+	options.DocumentField = ""
+	options.Schema = nil
+	options.DerivedSchema = nil
+	options.Columns = nil
+	options.ExcludeColumns = nil
+	options.OnlyColumns = false
+
+	children := make(map[string]*field.FieldBuilder)
+	for _, name := range names {
+		children[name] = field.NewFieldBuilderWithOptions(5, options)
+	}
+
+	return &ChildFramesBuilder{
+		names:    names,
+		children: children,
+		hexIds:   hexIds,
+	}
+}
+
+func (cb *ChildFramesBuilder) ProcessRawRecord(record bson.Raw) error {
+
+	var parentId interface{}
+	if value, err := record.LookupErr("_id"); err == nil {
+		if err := value.Unmarshal(&parentId); err != nil {
+			return err
+		}
+	}
+
+	// an _id with a creation time field is returned as hex
+	if id, ok := parentId.(primitive.ObjectID); ok && cb.hexIds {
+		parentId = id.Hex()
+	}
+
+	for _, name := range cb.names {
+
+		value, err := record.LookupErr(name)
+		if err != nil || value.Type != bsontype.Array {
+			continue
+		}
+
+		elements, err := value.Array().Values()
+		if err != nil {
+			return err
+		}
+
+		for _, element := range elements {
+
+			if element.Type != bsontype.EmbeddedDocument {
+				continue
+			}
+
+			var document primitive.D
+			if err := element.Unmarshal(&document); err != nil {
+				return err
+			}
+			row := append(primitive.D{{Key: ParentIdField, Value: parentId}}, document...)
+			cb.children[name].ProcessRecord(row)
+		}
+	}
+	return nil
+}
+
+func (cb *ChildFramesBuilder) Size() int {
+
+	size := 0
+	for _, child := range cb.children {
+		size += child.Size()
+	}
+	return size
+}
+
+// Build returns a frame named after each array field.
+func (cb *ChildFramesBuilder) Build() []*data.Frame {
+
+	frames := make([]*data.Frame, 0, len(cb.names))
+	for _, name := range cb.names {
+
+		child := cb.children[name]
+		frame := data.NewFrame(name, child.BuildFields()...)
+		if notices := child.Notices(); len(notices) > 0 {
+			frame.AppendNotices(notices...)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
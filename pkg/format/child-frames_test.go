@@ -0,0 +1,71 @@
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestChildFramesBuilder(t *testing.T) {
+
+	id1 := primitive.NewObjectID()
+	id2 := primitive.NewObjectID()
+	parentId := fmt.Sprintf("ObjectId(%q)", id1.Hex())
+	records := []primitive.D{
+		{{Key: "_id", Value: id1}, {Key: "lineItems", Value: primitive.A{
+			primitive.D{{Key: "sku", Value: "a"}, {Key: "qty", Value: int32(2)}, {Key: "price", Value: 1.5}},
+			primitive.D{{Key: "sku", Value: "b"}, {Key: "qty", Value: int32(1)}},
+			"ignored",
+		}}},
+		{{Key: "_id", Value: id2}, {Key: "lineItems", Value: "not an array"}},
+		{{Key: "lineItems", Value: primitive.A{
+			primitive.D{{Key: "sku", Value: "c"}, {Key: "qty", Value: int64(3)}},
+		}}},
+	}
+
+	want := []*data.Frame{
+		data.NewFrame("lineItems",
+			data.NewField(ParentIdField, nil, []*string{stringPtr(parentId), stringPtr(parentId), nil}),
+			data.NewField("sku", nil, []string{"a", "b", "c"}),
+			data.NewField("qty", nil, []int64{2, 1, 3}),
+			data.NewField("price", nil, []*float64{float64Ptr(1.5), nil, nil})),
+		data.NewFrame("tags", []*data.Field{}...),
+	}
+
+	cb := NewChildFramesBuilder([]string{"lineItems", "tags"}, field.Options{Schema: []field.Column{{Name: "total"}}})
+	for _, record := range records {
+		raw, err := bson.Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cb.ProcessRawRecord(raw); err != nil {
+			t.Errorf("cb.ProcessRawRecord(%v) error = %v", record, err)
+		}
+	}
+
+	if got := cb.Build(); !reflect.DeepEqual(got, want) {
+		t.Errorf("cb.Build() = %v", got)
+	}
+}
+
+func TestChildFramesBuilderObjectIdTime(t *testing.T) {
+
+	id := primitive.NewObjectID()
+	record, _ := bson.Marshal(primitive.D{{Key: "_id", Value: id}, {Key: "items", Value: primitive.A{primitive.D{}}}})
+
+	// the parent id matches the _id of the parent frame
+	cb := NewChildFramesBuilder([]string{"items"}, field.Options{ObjectIdTimeFields: []string{"_id"}})
+	if err := cb.ProcessRawRecord(record); err != nil {
+		t.Fatal(err)
+	}
+
+	want := data.NewFrame("items", data.NewField(ParentIdField, nil, []string{id.Hex()}))
+	if got := cb.Build()[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("cb.Build() = %v", got)
+	}
+}
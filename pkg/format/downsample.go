@@ -0,0 +1,222 @@
+package format
+
+import (
+	"math"
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+type DownsampleMode string
+
+const (
+	// DownsampleLTTB keeps the points selected by the Largest-Triangle-Three-
+	// Buckets algorithm.
+	DownsampleLTTB DownsampleMode = "lttb"
+
+	// DownsampleMinMax keeps the smallest and largest point of each bucket.
+	DownsampleMinMax DownsampleMode = "minmax"
+)
+
+func (m DownsampleMode) IsValid() bool {
+	return m == "" || m == DownsampleLTTB || m == DownsampleMinMax
+}
+
+// point is a row of a series with its time in milliseconds.
+type point struct {
+	row int
+	x   float64
+	y   float64
+}
+
+// Downsample reduces a frame sorted by time to at most maxPoints rows. Each
+// numeric field gets an equal share of the points and the rows selected for
+// any of the fields are kept, evenly thinned should the shares not divide
+// into whole points. The number of rows before and after are added to the
+// stats of the frame. Frames without a time field or numeric fields, or with
+// at most maxPoints rows, are returned unchanged.
+func Downsample(frame *data.Frame, mode DownsampleMode, maxPoints int) *data.Frame {
+
+	if mode == "" || maxPoints <= 0 || frame.Rows() <= maxPoints {
+		return frame
+	}
+
+	timeIndex, timeField, _ := findTimeField(frame, "")
+	if timeIndex == -1 {
+		return frame
+	}
+
+	var series []*data.Field
+	for _, field := range frame.Fields {
+		if field.Type().Numeric() {
+			series = append(series, field)
+		}
+	}
+
+	if len(series) == 0 {
+		return frame
+	}
+
+	budget := maxPoints / len(series)
+	if budget < 1 {
+		budget = 1
+	}
+
+	selected := make(map[int]bool)
+	for _, field := range series {
+
+		points := seriesPoints(timeField, field)
+		if len(points) > budget {
+			if mode == DownsampleMinMax {
+				points = minMaxPoints(points, budget)
+			} else {
+				points = lttbPoints(points, budget)
+			}
+		}
+		for _, p := range points {
+			selected[p.row] = true
+		}
+	}
+
+	rows := make([]int, 0, len(selected))
+	for row := range selected {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+
+	// small shares keep the first and last points of each series
+	if len(rows) > maxPoints {
+		thinned := make([]int, maxPoints)
+		for i := range thinned {
+			thinned[i] = rows[i*len(rows)/maxPoints]
+		}
+		rows = thinned
+	}
+
+	fields := make([]*data.Field, len(frame.Fields))
+	for i, field := range frame.Fields {
+		fields[i] = selectRows(field, rows)
+	}
+
+	meta := data.FrameMeta{}
+	if frame.Meta != nil {
+		meta = *frame.Meta
+	}
+	meta.Stats = append(append([]data.QueryStat{}, meta.Stats...),
+		data.QueryStat{FieldConfig: data.FieldConfig{DisplayName: "Points before downsampling"}, Value: float64(frame.Rows())},
+		data.QueryStat{FieldConfig: data.FieldConfig{DisplayName: "Points after downsampling"}, Value: float64(len(rows))},
+	)
+
+	return data.NewFrame(frame.Name, fields...).SetMeta(&meta)
+}
+
+// seriesPoints returns the rows of a field with a time and a value.
+func seriesPoints(timeField *data.Field, field *data.Field) []point {
+
+	points := make([]point, 0, field.Len())
+	for row := 0; row < field.Len(); row++ {
+
+		t, ok := timeAt(timeField, row)
+		if !ok {
+			continue
+		}
+
+		y, err := field.FloatAt(row)
+		if err != nil || math.IsNaN(y) || math.IsInf(y, 0) {
+			continue
+		}
+		points = append(points, point{row: row, x: float64(t.UnixNano()) / 1e6, y: y})
+	}
+	return points
+}
+
+// lttbPoints selects threshold points with the Largest-Triangle-Three-Buckets
+// algorithm. The first and last points are always kept and one point is kept
+// from each bucket in between, the one forming the largest triangle with the
+// point kept from the previous bucket and the average of the next bucket.
+func lttbPoints(points []point, threshold int) []point {
+
+	if threshold < 3 {
+		threshold = 3
+	}
+	if len(points) <= threshold {
+		return points
+	}
+
+	result := make([]point, 0, threshold)
+	result = append(result, points[0])
+
+	size := float64(len(points)-2) / float64(threshold-2)
+	previous := points[0]
+	for bucket := 0; bucket < threshold-2; bucket++ {
+
+		start := int(float64(bucket)*size) + 1
+		end := int(float64(bucket+1)*size) + 1
+
+		nextEnd := int(float64(bucket+2)*size) + 1
+		if nextEnd > len(points) {
+			nextEnd = len(points)
+		}
+		var avgX, avgY float64
+		for _, p := range points[end:nextEnd] {
+			avgX += p.x
+			avgY += p.y
+		}
+		avgX /= float64(nextEnd - end)
+		avgY /= float64(nextEnd - end)
+
+		selected, maxArea := points[start], -1.0
+		for _, p := range points[start:end] {
+			area := math.Abs((previous.x-avgX)*(p.y-previous.y) - (previous.x-p.x)*(avgY-previous.y))
+			if area > maxArea {
+				selected, maxArea = p, area
+			}
+		}
+
+		result = append(result, selected)
+		previous = selected
+	}
+
+	return append(result, points[len(points)-1])
+}
+
+// minMaxPoints splits the points into threshold/2 buckets and keeps the
+// smallest and largest point of each in time order.
+func minMaxPoints(points []point, threshold int) []point {
+
+	buckets := threshold / 2
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	result := make([]point, 0, buckets*2)
+	size := float64(len(points)) / float64(buckets)
+	for bucket := 0; bucket < buckets; bucket++ {
+
+		start := int(float64(bucket) * size)
+		end := int(float64(bucket+1) * size)
+		if start == end {
+			continue
+		}
+
+		min, max := points[start], points[start]
+		for _, p := range points[start:end] {
+			if p.y < min.y {
+				min = p
+			}
+			if p.y > max.y {
+				max = p
+			}
+		}
+
+		switch {
+		case min.row == max.row:
+			result = append(result, min)
+		case min.row < max.row:
+			result = append(result, min, max)
+		default:
+			result = append(result, max, min)
+		}
+	}
+	return result
+}
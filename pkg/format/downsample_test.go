@@ -0,0 +1,115 @@
+package format
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestDownsample(t *testing.T) {
+
+	times := make([]time.Time, 10)
+	for i := range times {
+		times[i] = time.Unix(int64(i), 0)
+	}
+	values := []float64{0, 1, 0, 5, 0, 1, -4, 1, 0, 1}
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+
+	input := func() *data.Frame {
+		return data.NewFrame("response",
+			data.NewField("time", nil, times),
+			data.NewField("value", nil, values),
+			data.NewField("name", nil, names))
+	}
+	output := func(rows ...int) *data.Frame {
+
+		frame := data.NewFrame("response")
+		for _, field := range input().Fields {
+			frame.Fields = append(frame.Fields, selectRows(field, rows))
+		}
+		return frame.SetMeta(&data.FrameMeta{Stats: []data.QueryStat{
+			{FieldConfig: data.FieldConfig{DisplayName: "Points before downsampling"}, Value: 10},
+			{FieldConfig: data.FieldConfig{DisplayName: "Points after downsampling"}, Value: float64(len(rows))},
+		}})
+	}
+
+	var tests = []struct {
+		input     *data.Frame
+		mode      DownsampleMode
+		maxPoints int
+		want      *data.Frame
+	}{
+		//Not downsampled
+		{input(), "", 4, input()},
+
+		//Fewer rows than the maximum
+		{input(), DownsampleLTTB, 10, input()},
+
+		//No numeric field
+		{data.NewFrame("response",
+			data.NewField("time", nil, times),
+			data.NewField("name", nil, names)), DownsampleLTTB, 4,
+			data.NewFrame("response",
+				data.NewField("time", nil, times),
+				data.NewField("name", nil, names))},
+
+		//Largest triangle three buckets keeps the peaks
+		{input(), DownsampleLTTB, 4, output(0, 3, 6, 9)},
+
+		//Min max buckets
+		{input(), DownsampleMinMax, 4, output(0, 3, 5, 6)},
+	}
+
+	for _, test := range tests {
+		if got := Downsample(test.input, test.mode, test.maxPoints); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Downsample(%v, %q, %d) = %v", test.input, test.mode, test.maxPoints, got)
+		}
+	}
+}
+
+func TestDownsampleSeries(t *testing.T) {
+
+	t1 := time.Unix(1, 0)
+	frame := data.NewFrame("response",
+		data.NewField("time", nil, []*time.Time{&t1, &t1, nil, &t1}),
+		data.NewField("a", nil, []*float64{float64Ptr(1), nil, float64Ptr(2), float64Ptr(3)}),
+		data.NewField("b", nil, []float64{1, 2, 3, 4}))
+
+	// rows with a null time or value are not points of a series
+	got := Downsample(frame, DownsampleMinMax, 2)
+	if values := got.Fields[2].At(1); got.Rows() != 2 || values != 4.0 {
+		t.Errorf("Downsample(%v) = %v", frame, got)
+	}
+}
+
+func TestDownsampleMaxPoints(t *testing.T) {
+
+	times := make([]time.Time, 1000)
+	a := make([]float64, len(times))
+	b := make([]float64, len(times))
+	c := make([]int64, len(times))
+	for i := range times {
+		times[i] = time.Unix(int64(i), 0)
+		a[i] = math.Sin(float64(i) / 10)
+		b[i] = math.Cos(float64(i) / 7)
+		c[i] = int64(i % 13)
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("time", nil, times),
+		data.NewField("a", nil, a),
+		data.NewField("b", nil, b),
+		data.NewField("c", nil, c))
+
+	for _, mode := range []DownsampleMode{DownsampleLTTB, DownsampleMinMax} {
+		for _, maxPoints := range []int{100, 5, 1} {
+			got := Downsample(frame, mode, maxPoints)
+			if rows := got.Rows(); rows == 0 || rows > maxPoints || got.Meta.Stats[1].Value != float64(rows) {
+				t.Errorf("Downsample(%q, %d) returned %d rows", mode, maxPoints, rows)
+			}
+		}
+	}
+}
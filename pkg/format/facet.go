@@ -0,0 +1,121 @@
+package format
+
+import (
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FacetBuilder converts the result of a $facet stage, a document whose
+// fields are arrays of sub-results, into one frame per facet named after its
+// field. The documents of every facet are built with the same options.
+type FacetBuilder struct {
+	options   field.Options
+	maxBytes  int
+	names     []string
+	facets    map[string]*field.FieldBuilder
+	truncated bool
+}
+
+// NewFacetBuilder returns a builder that stops adding documents once the
+// size of the facets exceeds maxBytes, 0 for no limit. As the result of a
+// $facet is a single document the limit applies within it. The declared and
+// derived schema and the selected columns of the query describe the $facet
+// document rather than the documents of each facet, so are not used.
+func NewFacetBuilder(options field.Options, maxBytes int) *FacetBuilder {
+
+	options.Schema = nil
+	options.DerivedSchema = nil
+	options.Columns = nil
+	options.OnlyColumns = false
+
+	return &FacetBuilder{
+		options:  options,
+		maxBytes: maxBytes,
+		facets:   make(map[string]*field.FieldBuilder),
+	}
+}
+
+// ProcessRecord adds the documents of each array field of a record to its
+// facet, other fields and array elements that are not documents are
+// ignored.
+func (fb *FacetBuilder) ProcessRecord(record primitive.D) {
+
+	for _, e := range record {
+
+		results, ok := e.Value.(primitive.A)
+		if !ok {
+			continue
+		}
+
+		facet := fb.facets[e.Key]
+		if facet == nil {
+			facet = field.NewFieldBuilderWithOptions(5, fb.options)
+			fb.facets[e.Key] = facet
+			fb.names = append(fb.names, e.Key)
+		}
+
+		for _, result := range results {
+
+			if fb.truncated {
+				return
+			}
+
+			if document, ok := result.(primitive.D); ok {
+				facet.ProcessRecord(document)
+				fb.truncated = fb.maxBytes > 0 && fb.Size() > fb.maxBytes
+			}
+		}
+	}
+}
+
+func (fb *FacetBuilder) Size() int {
+
+	size := 0
+	for _, facet := range fb.facets {
+		size += facet.Size()
+	}
+	return size
+}
+
+// Truncated reports whether documents were dropped to keep within the size
+// limit.
+func (fb *FacetBuilder) Truncated() bool {
+	return fb.truncated
+}
+
+// Build returns the frames of the facets in the order they were first seen.
+func (fb *FacetBuilder) Build() []*data.Frame {
+
+	frames := make([]*data.Frame, 0, len(fb.names))
+	for _, name := range fb.names {
+
+		facet := fb.facets[name]
+		frame := data.NewFrame(name, facet.BuildFields()...)
+		if notices := facet.Notices(); len(notices) > 0 {
+			frame.AppendNotices(notices...)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// HasFields reports whether a facet has the named fields and a time field
+// when requireTime is set or timeField is not empty. The time field is named
+// timeField, or is the first time field when timeField is empty. Facets
+// without the fields, such as a $count beside a time series, cannot be
+// pivoted, split into series or converted to a time series.
+func HasFields(frame *data.Frame, timeField string, requireTime bool, names ...string) bool {
+
+	for _, name := range names {
+		if fieldByName(frame, name) == nil {
+			return false
+		}
+	}
+
+	if !requireTime && timeField == "" {
+		return true
+	}
+	timeIndex, _, err := findTimeField(frame, timeField)
+	return err == nil && timeIndex != -1
+}
@@ -0,0 +1,130 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFacetBuilder(t *testing.T) {
+
+	var tests = []struct {
+		options field.Options
+		records []primitive.D
+		want    []*data.Frame
+	}{
+		//One frame per facet
+		{field.Options{},
+			[]primitive.D{
+				{{Key: "byStatus", Value: primitive.A{
+					primitive.D{{Key: "_id", Value: "ok"}, {Key: "count", Value: int32(3)}},
+					primitive.D{{Key: "_id", Value: "error"}, {Key: "count", Value: int32(1)}},
+				}},
+					{Key: "total", Value: primitive.A{
+						primitive.D{{Key: "count", Value: int32(4)}},
+						"ignored",
+					}},
+					{Key: "scalar", Value: int32(1)},
+					{Key: "empty", Value: primitive.A{}},
+				},
+			},
+			[]*data.Frame{
+				data.NewFrame("byStatus",
+					data.NewField("_id", nil, []string{"ok", "error"}),
+					data.NewField("count", nil, []int32{3, 1})),
+				data.NewFrame("total",
+					data.NewField("count", nil, []int32{4})),
+				data.NewFrame("empty", []*data.Field{}...),
+			}},
+
+		//Field options apply to each facet, the schema and columns do not
+		{field.Options{ExcludeColumns: []string{"_id"}, Schema: []field.Column{{Name: "total"}},
+			DerivedSchema: []field.Column{{Name: "a"}}, Columns: []field.OutputColumn{{Name: "x"}}, OnlyColumns: true},
+			[]primitive.D{
+				{{Key: "a", Value: primitive.A{primitive.D{{Key: "_id", Value: "x"}, {Key: "n", Value: int32(1)}}}}},
+				{{Key: "a", Value: primitive.A{primitive.D{{Key: "_id", Value: "y"}, {Key: "n", Value: int32(2)}}}}},
+			},
+			[]*data.Frame{
+				data.NewFrame("a",
+					data.NewField("n", nil, []int32{1, 2})),
+			}},
+	}
+
+	for _, test := range tests {
+
+		fb := NewFacetBuilder(test.options, 0)
+		for _, record := range test.records {
+			fb.ProcessRecord(record)
+		}
+
+		if got := fb.Build(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v fb.Build() = %v", test.records, got)
+		}
+	}
+}
+
+func TestFacetBuilderMaxBytes(t *testing.T) {
+
+	results := make(primitive.A, 100)
+	for i := range results {
+		results[i] = primitive.D{{Key: "n", Value: int64(i)}}
+	}
+
+	fb := NewFacetBuilder(field.Options{}, 80)
+	fb.ProcessRecord(primitive.D{{Key: "a", Value: results}, {Key: "b", Value: results}})
+
+	want := []*data.Frame{
+		data.NewFrame("a",
+			data.NewField("n", nil, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10})),
+	}
+	if got := fb.Build(); !fb.Truncated() || !reflect.DeepEqual(got, want) {
+		t.Errorf("fb.Build() = %v, truncated %v", got, fb.Truncated())
+	}
+}
+
+func TestHasFields(t *testing.T) {
+
+	fb := NewFacetBuilder(field.Options{}, 0)
+	fb.ProcessRecord(primitive.D{
+		{Key: "series", Value: primitive.A{
+			primitive.D{{Key: "ts", Value: primitive.DateTime(1000)}, {Key: "host", Value: "a"}, {Key: "cpu", Value: 1.5}},
+		}},
+		{Key: "total", Value: primitive.A{primitive.D{{Key: "count", Value: int32(4)}}}},
+		{Key: "byHost", Value: primitive.A{primitive.D{{Key: "_id", Value: "a"}, {Key: "count", Value: int32(4)}}}},
+	})
+	facets := fb.Build()
+	series, total, byHost := facets[0], facets[1], facets[2]
+
+	var tests = []struct {
+		frame       *data.Frame
+		timeField   string
+		requireTime bool
+		names       []string
+		want        bool
+	}{
+		//No fields required
+		{total, "", false, nil, true},
+
+		//Any time field
+		{series, "", true, nil, true},
+		{total, "", true, nil, false},
+
+		//Named time field
+		{series, "ts", false, nil, true},
+		{byHost, "ts", false, nil, false},
+		{series, "host", false, nil, false},
+
+		//Pivot and series by fields
+		{series, "", true, []string{"host", "cpu"}, true},
+		{byHost, "", false, []string{"host"}, false},
+	}
+
+	for _, test := range tests {
+		if got := HasFields(test.frame, test.timeField, test.requireTime, test.names...); got != test.want {
+			t.Errorf("HasFields(%v, %q, %v, %v) = %v", test.frame.Name, test.timeField, test.requireTime, test.names, got)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package format
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Format string
+
+const (
+	// Table returns the documents as a single frame with one column per field.
+	Table Format = "table"
+
+	// TimeSeries returns the documents as a wide time series frame.
+	TimeSeries Format = "time_series"
+
+	// Logs returns the documents as log lines for the logs visualization.
+	Logs Format = "logs"
+
+	// Trace returns span documents as a trace for the trace view.
+	Trace Format = "trace"
+
+	// NodeGraph returns the documents as the nodes and edges of a node graph.
+	NodeGraph Format = "nodeGraph"
+
+	// Histogram returns the results of a $bucket or $bucketAuto stage as the
+	// buckets of a histogram.
+	Histogram Format = "histogram"
+
+	// Heatmap returns the counts of buckets over time as a heatmap.
+	Heatmap Format = "heatmap"
+)
+
+func (f Format) IsValid() bool {
+
+	switch f {
+	case "", Table, TimeSeries, Logs, Trace, NodeGraph, Histogram, Heatmap:
+		return true
+	default:
+		return false
+	}
+}
+
+func fieldByName(frame *data.Frame, name string) *data.Field {
+
+	for _, field := range frame.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+func isTime(fieldType data.FieldType) bool {
+	return fieldType == data.FieldTypeTime || fieldType == data.FieldTypeNullableTime
+}
+
+func isDimension(fieldType data.FieldType) bool {
+
+	switch fieldType {
+	case data.FieldTypeString, data.FieldTypeNullableString, data.FieldTypeBool, data.FieldTypeNullableBool:
+		return true
+	default:
+		return false
+	}
+}
+
+func timeAt(field *data.Field, row int) (time.Time, bool) {
+
+	value, ok := field.ConcreteAt(row)
+	if !ok {
+		return time.Time{}, false
+	}
+	return value.(time.Time), true
+}
+
+// sortedTimeRows returns the indexes of the rows with a non null time
+// ordered by time, ascending or descending.
+func sortedTimeRows(timeField *data.Field, ascending bool) []int {
+
+	rows := make([]int, 0, timeField.Len())
+	times := make([]time.Time, timeField.Len())
+	for i := 0; i < timeField.Len(); i++ {
+		t, ok := timeAt(timeField, i)
+		if ok {
+			times[i] = t
+			rows = append(rows, i)
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if ascending {
+			return times[rows[i]].Before(times[rows[j]])
+		}
+		return times[rows[i]].After(times[rows[j]])
+	})
+	return rows
+}
+
+// selectRows returns a copy of the field containing only the given rows in
+// the given order.
+func selectRows(field *data.Field, rows []int) *data.Field {
+
+	result := data.NewFieldFromFieldType(field.Type(), len(rows))
+	result.Name = field.Name
+	result.Labels = field.Labels
+	result.Config = field.Config
+	for i, row := range rows {
+		result.Set(i, field.CopyAt(row))
+	}
+	return result
+}
+
+func isDateTime(value interface{}) bool {
+	_, ok := value.(primitive.DateTime)
+	return ok
+}
+
+func isScalar(value interface{}) bool {
+
+	switch value.(type) {
+	case nil, primitive.D, primitive.M, primitive.A:
+		return false
+	default:
+		return true
+	}
+}
+
+func idValue(value interface{}, options field.Options) string {
+
+	id, ok := value.(primitive.ObjectID)
+	if ok {
+		return id.Hex()
+	}
+	return field.StringValueWithOptions(value, options)
+}
+
+// valueOptions returns the options converting the values of a query, for
+// builders whose fields are set by the format rather than by the schema and
+// columns of the query.
+func valueOptions(options field.Options) field.Options {
+
+	return field.Options{
+		Decimal128:         options.Decimal128,
+		ExactDecimalFields: options.ExactDecimalFields,
+		ObjectIdTimeFields: options.ObjectIdTimeFields,
+		UUIDRepresentation: options.UUIDRepresentation,
+		DetectGeoJSON:      options.DetectGeoJSON,
+		GeoJSONFields:      options.GeoJSONFields,
+	}
+}
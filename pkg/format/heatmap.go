@@ -0,0 +1,162 @@
+package format
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultHeatmapBucketField = "bucket"
+	DefaultHeatmapCountField  = "count"
+)
+
+// frameTypeHeatmapRows is the frame type of a heatmap with a time field and
+// one count field per bucket.
+const frameTypeHeatmapRows data.FrameType = "heatmap-rows"
+
+// HeatmapMapping names the document fields that hold each part of a heatmap
+// cell. The fields of an _id document, such as the _id of a $group on time
+// and bucket, are found as well as top level fields.
+type HeatmapMapping struct {
+	// TimeField defaults to the first date field of each document.
+	TimeField   string
+	BucketField string
+	CountField  string
+}
+
+// HeatmapBuilder converts documents holding the count of a bucket at a time
+// into a frame with a time field and one count field per bucket.
+type HeatmapBuilder struct {
+	mapping HeatmapMapping
+	options field.Options
+	counts  map[int64]map[string]float64
+	buckets map[string]interface{}
+}
+
+func NewHeatmapBuilder(mapping HeatmapMapping, options field.Options) *HeatmapBuilder {
+
+	mapping.BucketField = defaultName(mapping.BucketField, DefaultHeatmapBucketField)
+	mapping.CountField = defaultName(mapping.CountField, DefaultHeatmapCountField)
+
+	return &HeatmapBuilder{
+		mapping: mapping,
+		options: valueOptions(options),
+		counts:  make(map[int64]map[string]float64),
+		buckets: make(map[string]interface{}),
+	}
+}
+
+// ProcessRecord adds the count of a document to its cell, documents without
+// a time, bucket or numeric count are dropped.
+func (hb *HeatmapBuilder) ProcessRecord(record primitive.D) {
+
+	var timestamp, bucket, count interface{}
+	elements := record
+	if id, ok := record.Map()["_id"].(primitive.D); ok {
+		elements = append(append(primitive.D{}, id...), record...)
+	}
+
+	for _, e := range elements {
+		switch {
+		case e.Key == hb.mapping.TimeField:
+			timestamp = e.Value
+		case hb.mapping.TimeField == "" && timestamp == nil && isDateTime(e.Value):
+			timestamp = e.Value
+		case e.Key == hb.mapping.BucketField:
+			bucket = e.Value
+		case e.Key == hb.mapping.CountField:
+			count = e.Value
+		}
+	}
+
+	t, ok := heatmapTime(timestamp)
+	if !ok || bucket == nil {
+		return
+	}
+
+	value, ok := field.FloatValue(count)
+	if !ok {
+		return
+	}
+
+	name := field.StringValueWithOptions(bucket, hb.options)
+	hb.buckets[name] = bucket
+	key := t.UnixNano()
+	if hb.counts[key] == nil {
+		hb.counts[key] = make(map[string]float64)
+	}
+	hb.counts[key][name] += value
+}
+
+// Build returns the heatmap frame sorted by time with the buckets in
+// ascending order, numerically when every bucket is a number. Buckets
+// without a count at a time are zero.
+func (hb *HeatmapBuilder) Build() *data.Frame {
+
+	keys := make([]int64, 0, len(hb.counts))
+	for key := range hb.counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	times := make([]time.Time, len(keys))
+	for i, key := range keys {
+		times[i] = time.Unix(0, key)
+	}
+
+	fields := []*data.Field{data.NewField("time", nil, times)}
+	for _, name := range hb.sortedBuckets() {
+
+		counts := make([]float64, len(times))
+		for i, key := range keys {
+			counts[i] = hb.counts[key][name]
+		}
+		fields = append(fields, data.NewField(name, nil, counts))
+	}
+
+	frame := data.NewFrame("response", fields...)
+	frame.Meta = &data.FrameMeta{Type: frameTypeHeatmapRows}
+	return frame
+}
+
+func (hb *HeatmapBuilder) sortedBuckets() []string {
+
+	names := make([]string, 0, len(hb.buckets))
+	numbers := make(map[string]float64)
+	for name, bucket := range hb.buckets {
+		names = append(names, name)
+		if number, ok := field.FloatValue(bucket); ok {
+			numbers[name] = number
+		}
+	}
+
+	numeric := len(numbers) == len(names)
+	sort.Slice(names, func(i, j int) bool {
+		if numeric {
+			return numbers[names[i]] < numbers[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// heatmapTime converts a date or an epoch time in seconds or milliseconds.
+func heatmapTime(value interface{}) (time.Time, bool) {
+
+	switch value := value.(type) {
+	case primitive.DateTime:
+		return value.Time(), true
+	case time.Time:
+		return value, true
+	default:
+		epoch, ok := field.FloatValue(value)
+		if !ok {
+			return time.Time{}, false
+		}
+		return guessEpochTime(epoch), true
+	}
+}
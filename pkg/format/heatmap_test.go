@@ -0,0 +1,66 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHeatmapBuilder(t *testing.T) {
+
+	t1 := time.Unix(60, 0)
+	t2 := time.Unix(120, 0)
+	t3 := time.Unix(1620586368, 0)
+	meta := &data.FrameMeta{Type: frameTypeHeatmapRows}
+
+	var tests = []struct {
+		mapping HeatmapMapping
+		records []primitive.D
+		want    *data.Frame
+	}{
+		//$group on time and bucket
+		{HeatmapMapping{},
+			[]primitive.D{
+				{{Key: "_id", Value: primitive.D{{Key: "time", Value: primitive.NewDateTimeFromTime(t2)}, {Key: "bucket", Value: int32(100)}}},
+					{Key: "count", Value: int32(2)}},
+				{{Key: "_id", Value: primitive.D{{Key: "time", Value: primitive.NewDateTimeFromTime(t1)}, {Key: "bucket", Value: int32(20)}}},
+					{Key: "count", Value: int32(4)}},
+				{{Key: "_id", Value: primitive.D{{Key: "time", Value: primitive.NewDateTimeFromTime(t1)}, {Key: "bucket", Value: int32(100)}}},
+					{Key: "count", Value: int64(1)}},
+				{{Key: "_id", Value: primitive.D{{Key: "time", Value: primitive.NewDateTimeFromTime(t1)}}},
+					{Key: "count", Value: int32(9)}},
+			},
+			data.NewFrame("response",
+				data.NewField("time", nil, []time.Time{t1, t2}),
+				data.NewField("20", nil, []float64{4, 0}),
+				data.NewField("100", nil, []float64{1, 2})).SetMeta(meta)},
+
+		//Named fields with epoch times and string buckets
+		{HeatmapMapping{TimeField: "ts", BucketField: "le", CountField: "n"},
+			[]primitive.D{
+				{{Key: "ts", Value: int64(1620586368000)}, {Key: "le", Value: "b"}, {Key: "n", Value: 1.5}},
+				{{Key: "ts", Value: int64(1620586368000)}, {Key: "le", Value: "a"}, {Key: "n", Value: int32(1)}},
+				{{Key: "ts", Value: int64(1620586368000)}, {Key: "le", Value: "a"}, {Key: "n", Value: int32(2)}},
+			},
+			data.NewFrame("response",
+				data.NewField("time", nil, []time.Time{t3}),
+				data.NewField("a", nil, []float64{3}),
+				data.NewField("b", nil, []float64{1.5})).SetMeta(meta)},
+	}
+
+	for _, test := range tests {
+
+		hb := NewHeatmapBuilder(test.mapping, field.Options{})
+		for _, record := range test.records {
+			hb.ProcessRecord(record)
+		}
+
+		if got := hb.Build(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v hb.Build() = %v", test.records, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package format
+
+import (
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HistogramBuilder converts the results of a $bucket or $bucketAuto stage
+// into the xMin, xMax and value fields used by the histogram visualization.
+// The _id of each document is either the lower bound of its bucket, as
+// returned by $bucket, or a document with min and max fields, as returned by
+// $bucketAuto. Documents whose _id is not a bound, such as the default bucket
+// of $bucket, are dropped.
+type HistogramBuilder struct {
+	boundaries []float64
+	buckets    []histogramBucket
+	values     *field.FieldBuilder
+}
+
+type histogramBucket struct {
+	min float64
+	max *float64
+}
+
+// NewHistogramBuilder returns a builder taking the upper bound of each lower
+// bound from the boundaries of the $bucket stage of the query, nil when they
+// are not known.
+func NewHistogramBuilder(boundaries primitive.A, options field.Options) *HistogramBuilder {
+
+	var bounds []float64
+	for _, boundary := range boundaries {
+		bound, ok := field.FloatValue(boundary)
+		if !ok {
+			bounds = nil
+			break
+		}
+		bounds = append(bounds, bound)
+	}
+
+	return &HistogramBuilder{
+		boundaries: bounds,
+		values:     field.NewFieldBuilderWithOptions(5, valueOptions(options)),
+	}
+}
+
+func (hb *HistogramBuilder) ProcessRecord(record primitive.D) {
+
+	values := make(primitive.D, 0, len(record))
+	var bucket histogramBucket
+	ok := false
+	for _, e := range record {
+		if e.Key == "_id" {
+			bucket, ok = bucketBounds(e.Value)
+		} else {
+			values = append(values, e)
+		}
+	}
+
+	if ok {
+		hb.buckets = append(hb.buckets, bucket)
+		hb.values.ProcessRecord(values)
+	}
+}
+
+// Build returns the histogram frame. Without the boundaries of the query the
+// upper bound of a lower bound is the lower bound of the next bucket, so a
+// bucket spans any empty buckets after it as $bucket omits them, and the last
+// bucket has the width of the bucket before it.
+func (hb *HistogramBuilder) Build() *data.Frame {
+
+	xMin := make([]float64, len(hb.buckets))
+	xMax := make([]float64, len(hb.buckets))
+	for i, bucket := range hb.buckets {
+
+		xMin[i] = bucket.min
+		upper, ok := hb.upperBound(bucket.min)
+		switch {
+		case bucket.max != nil:
+			xMax[i] = *bucket.max
+		case ok:
+			xMax[i] = upper
+		case i+1 < len(hb.buckets):
+			xMax[i] = hb.buckets[i+1].min
+		case i > 0:
+			xMax[i] = bucket.min + bucket.min - xMin[i-1]
+		default:
+			xMax[i] = bucket.min
+		}
+	}
+
+	fields := append([]*data.Field{
+		data.NewField("xMin", nil, xMin),
+		data.NewField("xMax", nil, xMax),
+	}, hb.values.BuildFields()...)
+	return data.NewFrame("response", fields...)
+}
+
+// upperBound returns the boundary after a lower bound.
+func (hb *HistogramBuilder) upperBound(min float64) (float64, bool) {
+
+	for _, boundary := range hb.boundaries {
+		if boundary > min {
+			return boundary, true
+		}
+	}
+	return 0, false
+}
+
+func bucketBounds(id interface{}) (histogramBucket, bool) {
+
+	if min, ok := field.FloatValue(id); ok {
+		return histogramBucket{min: min}, true
+	}
+
+	document, ok := id.(primitive.D)
+	if !ok {
+		return histogramBucket{}, false
+	}
+
+	values := document.Map()
+	min, ok := field.FloatValue(values["min"])
+	if !ok {
+		return histogramBucket{}, false
+	}
+
+	bucket := histogramBucket{min: min}
+	if max, ok := field.FloatValue(values["max"]); ok {
+		bucket.max = &max
+	}
+	return bucket, true
+}
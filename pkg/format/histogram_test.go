@@ -0,0 +1,114 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHistogramBuilder(t *testing.T) {
+
+	var tests = []struct {
+		boundaries primitive.A
+		records    []primitive.D
+		want       *data.Frame
+	}{
+		//$bucket lower bounds, the default bucket is dropped
+		{nil, []primitive.D{
+			{{Key: "_id", Value: int32(0)}, {Key: "count", Value: int32(3)}},
+			{{Key: "_id", Value: int32(10)}, {Key: "count", Value: int32(5)}},
+			{{Key: "_id", Value: int32(20)}, {Key: "count", Value: int32(1)}},
+			{{Key: "_id", Value: "Other"}, {Key: "count", Value: int32(7)}},
+		},
+			data.NewFrame("response",
+				data.NewField("xMin", nil, []float64{0, 10, 20}),
+				data.NewField("xMax", nil, []float64{10, 20, 30}),
+				data.NewField("count", nil, []int32{3, 5, 1}))},
+
+		//$bucket lower bounds with the boundaries of the query
+		{primitive.A{int32(0), int32(10), int32(20), 25.5}, []primitive.D{
+			{{Key: "_id", Value: int32(0)}, {Key: "count", Value: int32(3)}},
+			{{Key: "_id", Value: int32(10)}, {Key: "count", Value: int32(5)}},
+			{{Key: "_id", Value: int32(20)}, {Key: "count", Value: int32(1)}},
+		},
+			data.NewFrame("response",
+				data.NewField("xMin", nil, []float64{0, 10, 20}),
+				data.NewField("xMax", nil, []float64{10, 20, 25.5}),
+				data.NewField("count", nil, []int32{3, 5, 1}))},
+
+		//Empty buckets are omitted, the boundaries keep the widths
+		{primitive.A{int32(0), int32(10), int32(20), int32(30)}, []primitive.D{
+			{{Key: "_id", Value: int32(0)}, {Key: "count", Value: int32(3)}},
+			{{Key: "_id", Value: int32(20)}, {Key: "count", Value: int32(1)}},
+		},
+			data.NewFrame("response",
+				data.NewField("xMin", nil, []float64{0, 20}),
+				data.NewField("xMax", nil, []float64{10, 30}),
+				data.NewField("count", nil, []int32{3, 1}))},
+
+		//Empty buckets are omitted, without boundaries a bucket spans the gap
+		{nil, []primitive.D{
+			{{Key: "_id", Value: int32(0)}, {Key: "count", Value: int32(3)}},
+			{{Key: "_id", Value: int32(20)}, {Key: "count", Value: int32(1)}},
+		},
+			data.NewFrame("response",
+				data.NewField("xMin", nil, []float64{0, 20}),
+				data.NewField("xMax", nil, []float64{20, 40}),
+				data.NewField("count", nil, []int32{3, 1}))},
+
+		//$bucketAuto bounds with an accumulator
+		{nil, []primitive.D{
+			{{Key: "_id", Value: primitive.D{{Key: "min", Value: 0.5}, {Key: "max", Value: int64(2)}}},
+				{Key: "count", Value: int32(2)}, {Key: "avg", Value: 1.5}},
+			{{Key: "_id", Value: primitive.D{{Key: "min", Value: int64(2)}, {Key: "max", Value: 4.5}}},
+				{Key: "count", Value: int32(4)}},
+		},
+			data.NewFrame("response",
+				data.NewField("xMin", nil, []float64{0.5, 2}),
+				data.NewField("xMax", nil, []float64{2, 4.5}),
+				data.NewField("count", nil, []int32{2, 4}),
+				data.NewField("avg", nil, []*float64{float64Ptr(1.5), nil}))},
+
+		//Single bucket
+		{nil, []primitive.D{
+			{{Key: "_id", Value: 1.5}, {Key: "count", Value: int32(1)}},
+		},
+			data.NewFrame("response",
+				data.NewField("xMin", nil, []float64{1.5}),
+				data.NewField("xMax", nil, []float64{1.5}),
+				data.NewField("count", nil, []int32{1}))},
+	}
+
+	for _, test := range tests {
+
+		hb := NewHistogramBuilder(test.boundaries, field.Options{})
+		for _, record := range test.records {
+			hb.ProcessRecord(record)
+		}
+
+		if got := hb.Build(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v hb.Build() = %v", test.records, got)
+		}
+	}
+}
+
+func TestHistogramBuilderOptions(t *testing.T) {
+
+	total, _ := primitive.ParseDecimal128("12.34")
+
+	hb := NewHistogramBuilder(nil, field.Options{Decimal128: field.Decimal128Float})
+	hb.ProcessRecord(primitive.D{{Key: "_id", Value: int32(0)}, {Key: "total", Value: total}})
+
+	// the values are converted with the field options of the query
+	want := data.NewFrame("response",
+		data.NewField("xMin", nil, []float64{0}),
+		data.NewField("xMax", nil, []float64{0}),
+		data.NewField("total", nil, []float64{12.34}))
+
+	if got := hb.Build(); !reflect.DeepEqual(got, want) {
+		t.Errorf("hb.Build() = %v", got)
+	}
+}
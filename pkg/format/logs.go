@@ -0,0 +1,118 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultLogsBodyField     = "message"
+	DefaultLogsSeverityField = "level"
+)
+
+// frameTypeLogLines marks a frame as following the logs data plane contract,
+// without it Grafana reads the frame as a legacy logs frame and ignores the
+// severity and labels fields.
+const frameTypeLogLines data.FrameType = "log-lines"
+
+// LogsMapping names the document fields that hold each part of a log line.
+type LogsMapping struct {
+	// TimeField defaults to the first date field of each document.
+	TimeField     string
+	BodyField     string
+	SeverityField string
+}
+
+// LogsBuilder converts documents into a frame that follows the logs data
+// plane contract: timestamp, body, severity, id and labels fields. The
+// remaining scalar fields of each document become its labels.
+type LogsBuilder struct {
+	mapping LogsMapping
+	options field.Options
+	fields  *field.FieldBuilder
+}
+
+func NewLogsBuilder(mapping LogsMapping, options field.Options) *LogsBuilder {
+
+	if mapping.BodyField == "" {
+		mapping.BodyField = DefaultLogsBodyField
+	}
+
+	if mapping.SeverityField == "" {
+		mapping.SeverityField = DefaultLogsSeverityField
+	}
+
+	options = valueOptions(options)
+	return &LogsBuilder{
+		mapping: mapping,
+		options: options,
+		fields:  field.NewFieldBuilderWithOptions(5, options),
+	}
+}
+
+func (lb *LogsBuilder) ProcessRecord(record primitive.D) {
+
+	var timestamp interface{}
+	body := ""
+	line := make(primitive.D, 0, 5)
+	labels := make(map[string]string)
+
+	for _, e := range record {
+
+		switch {
+		case e.Key == lb.mapping.TimeField:
+			timestamp = e.Value
+
+		case lb.mapping.TimeField == "" && timestamp == nil && isDateTime(e.Value):
+			timestamp = e.Value
+
+		case e.Key == lb.mapping.BodyField:
+			body = field.StringValueWithOptions(e.Value, lb.options)
+
+		case e.Key == lb.mapping.SeverityField:
+			line = append(line, primitive.E{Key: "severity", Value: field.StringValueWithOptions(e.Value, lb.options)})
+
+		case e.Key == "_id":
+			line = append(line, primitive.E{Key: "id", Value: idValue(e.Value, lb.options)})
+
+		case isScalar(e.Value):
+			labels[e.Key] = field.StringValueWithOptions(e.Value, lb.options)
+		}
+	}
+
+	labelsJSON, _ := json.Marshal(labels)
+	line = append(primitive.D{
+		{Key: "timestamp", Value: timestamp},
+		{Key: "body", Value: body},
+	}, line...)
+	line = append(line, primitive.E{Key: "labels", Value: json.RawMessage(labelsJSON)})
+	lb.fields.ProcessRecord(line)
+}
+
+// Build returns the log lines sorted newest first, lines without a
+// timestamp are dropped.
+func (lb *LogsBuilder) Build() (*data.Frame, error) {
+
+	frame := data.NewFrame("response", lb.fields.BuildFields()...)
+	frame.Meta = &data.FrameMeta{Type: frameTypeLogLines, PreferredVisualization: data.VisTypeLogs}
+	if frame.Rows() == 0 {
+		return frame, nil
+	}
+
+	timeField := frame.Fields[0]
+	if !isTime(timeField.Type()) {
+		return nil, errors.New("the logs format requires a time field")
+	}
+
+	rows := sortedTimeRows(timeField, false)
+	fields := []*data.Field{nonNullableTime(selectRows(timeField, rows))}
+	for _, field := range frame.Fields[1:] {
+		fields = append(fields, selectRows(field, rows))
+	}
+	frame.Fields = fields
+	return frame, nil
+}
@@ -0,0 +1,110 @@
+package format
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLogsBuilder(t *testing.T) {
+
+	t1 := time.Unix(10, 0)
+	t2 := time.Unix(20, 0)
+	id := primitive.NewObjectID()
+	logsMeta := &data.FrameMeta{Type: frameTypeLogLines, PreferredVisualization: data.VisTypeLogs}
+
+	var tests = []struct {
+		mapping LogsMapping
+		records []primitive.D
+		want    *data.Frame
+		err     string
+	}{
+		//No documents
+		{LogsMapping{},
+			nil,
+			data.NewFrame("response", []*data.Field{}...).SetMeta(logsMeta), ""},
+
+		//No time field
+		{LogsMapping{},
+			[]primitive.D{
+				{{Key: "message", Value: "hello"}},
+			},
+			nil, "the logs format requires a time field"},
+
+		//Default mapping sorted newest first with labels
+		{LogsMapping{},
+			[]primitive.D{
+				{{Key: "_id", Value: id}, {Key: "ts", Value: primitive.NewDateTimeFromTime(t1)}, {Key: "message", Value: "first"},
+					{Key: "level", Value: "info"}, {Key: "host", Value: "a"}, {Key: "tags", Value: primitive.A{"x"}}},
+				{{Key: "ts", Value: primitive.NewDateTimeFromTime(t2)}, {Key: "message", Value: "second"},
+					{Key: "level", Value: "error"}, {Key: "count", Value: int32(3)}},
+			},
+			data.NewFrame("response",
+				data.NewField("timestamp", nil, []time.Time{t2, t1}),
+				data.NewField("body", nil, []string{"second", "first"}),
+				data.NewField("id", nil, []*string{nil, stringPtr(id.Hex())}),
+				data.NewField("severity", nil, []string{"error", "info"}),
+				data.NewField("labels", nil, []json.RawMessage{json.RawMessage(`{"count":"3"}`), json.RawMessage(`{"host":"a"}`)}),
+			).SetMeta(logsMeta), ""},
+
+		//Custom mapping, documents without a timestamp are dropped
+		{LogsMapping{TimeField: "when", BodyField: "msg", SeverityField: "lvl"},
+			[]primitive.D{
+				{{Key: "created", Value: primitive.NewDateTimeFromTime(t2)}, {Key: "when", Value: primitive.NewDateTimeFromTime(t1)},
+					{Key: "msg", Value: "first"}, {Key: "lvl", Value: "info"}},
+				{{Key: "msg", Value: "second"}},
+			},
+			data.NewFrame("response",
+				data.NewField("timestamp", nil, []time.Time{t1}),
+				data.NewField("body", nil, []string{"first"}),
+				data.NewField("severity", nil, []*string{stringPtr("info")}),
+				data.NewField("labels", nil, []json.RawMessage{json.RawMessage(`{"created":"1970-01-01T00:00:20Z"}`)}),
+			).SetMeta(logsMeta), ""},
+	}
+
+	for _, test := range tests {
+
+		lb := NewLogsBuilder(test.mapping, field.Options{})
+		for _, record := range test.records {
+			lb.ProcessRecord(record)
+		}
+
+		got, err := lb.Build()
+		if err != nil && err.Error() != test.err || err == nil && test.err != "" {
+			t.Errorf("LogsBuilder.Build(%v) error = %v", test.records, err)
+		} else if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("LogsBuilder.Build(%v) = %v", test.records, got)
+		}
+	}
+}
+
+func TestLogsBuilderOptions(t *testing.T) {
+
+	t1 := time.Unix(10, 0)
+	uuid := primitive.Binary{Subtype: 3, Data: []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}}
+
+	lb := NewLogsBuilder(LogsMapping{}, field.Options{UUIDRepresentation: field.UUIDJavaLegacy})
+	lb.ProcessRecord(primitive.D{{Key: "_id", Value: uuid}, {Key: "ts", Value: primitive.NewDateTimeFromTime(t1)},
+		{Key: "message", Value: "hello"}, {Key: "user", Value: uuid}})
+
+	// legacy UUIDs are rendered in the representation of the datasource
+	want := data.NewFrame("response",
+		data.NewField("timestamp", nil, []time.Time{t1}),
+		data.NewField("body", nil, []string{"hello"}),
+		data.NewField("id", nil, []string{"07060504-0302-0100-0f0e-0d0c0b0a0908"}),
+		data.NewField("labels", nil, []json.RawMessage{json.RawMessage(`{"user":"07060504-0302-0100-0f0e-0d0c0b0a0908"}`)}),
+	).SetMeta(&data.FrameMeta{Type: frameTypeLogLines, PreferredVisualization: data.VisTypeLogs})
+
+	if got, err := lb.Build(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LogsBuilder.Build() = %v, %v", got, err)
+	}
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
@@ -0,0 +1,191 @@
+package format
+
+import (
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NodeGraphMapping names the document fields that hold each part of a node
+// and its edges.
+type NodeGraphMapping struct {
+	// IdField defaults to _id.
+	IdField            string
+	TitleField         string
+	SubtitleField      string
+	MainStatField      string
+	SecondaryStatField string
+
+	// ParentField holds the id, or an array of ids, of the parents of a node.
+	ParentField string
+
+	// EdgesField holds an array of edges from a node. Each edge is either the
+	// id of the target node, a document with a target field, or an embedded
+	// node document such as the results of a $graphLookup.
+	EdgesField string
+}
+
+// NodeGraphBuilder converts documents into the nodes and edges frames used
+// by the node graph visualization.
+type NodeGraphBuilder struct {
+	mapping   NodeGraphMapping
+	options   field.Options
+	nodes     *field.FieldBuilder
+	edges     *field.FieldBuilder
+	nodeIds   map[string]bool
+	edgeIds   map[[2]string]bool
+	endpoints []string
+}
+
+func NewNodeGraphBuilder(mapping NodeGraphMapping, options field.Options) *NodeGraphBuilder {
+
+	mapping.IdField = defaultName(mapping.IdField, "_id")
+	options = valueOptions(options)
+
+	return &NodeGraphBuilder{
+		mapping: mapping,
+		options: options,
+		nodes:   field.NewFieldBuilderWithOptions(5, options),
+		edges:   field.NewFieldBuilderWithOptions(3, options),
+		nodeIds: make(map[string]bool),
+		edgeIds: make(map[[2]string]bool),
+	}
+}
+
+func (nb *NodeGraphBuilder) ProcessRecord(record primitive.D) {
+	nb.processNode(record)
+}
+
+// Build returns the nodes and edges frames. Nodes that are only referenced
+// by an edge are added using their id as the title.
+func (nb *NodeGraphBuilder) Build() []*data.Frame {
+
+	for _, id := range nb.endpoints {
+		if !nb.nodeIds[id] {
+			nb.nodeIds[id] = true
+			node := primitive.D{{Key: "id", Value: id}}
+			if nb.mapping.TitleField != "" {
+				node = append(node, primitive.E{Key: "title", Value: id})
+			}
+			nb.nodes.ProcessRecord(node)
+		}
+	}
+
+	meta := &data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph}
+	return []*data.Frame{
+		data.NewFrame("nodes", nb.nodes.BuildFields()...).SetMeta(meta),
+		data.NewFrame("edges", nb.edges.BuildFields()...).SetMeta(meta),
+	}
+}
+
+func (nb *NodeGraphBuilder) processNode(record primitive.D) (string, bool) {
+
+	values := record.Map()
+	value, ok := values[nb.mapping.IdField]
+	if !ok || value == nil {
+		return "", false
+	}
+
+	id := idValue(value, nb.options)
+	if !nb.nodeIds[id] {
+		nb.nodeIds[id] = true
+		nb.nodes.ProcessRecord(nb.node(id, values))
+	}
+
+	if nb.mapping.ParentField != "" {
+		for _, parent := range asArray(values[nb.mapping.ParentField]) {
+			nb.addEdge(idValue(parent, nb.options), id)
+		}
+	}
+
+	if nb.mapping.EdgesField != "" {
+		for _, edge := range asArray(values[nb.mapping.EdgesField]) {
+			nb.processEdge(id, edge)
+		}
+	}
+	return id, true
+}
+
+func (nb *NodeGraphBuilder) processEdge(source string, edge interface{}) {
+
+	document, ok := edge.(primitive.D)
+	if !ok {
+		nb.addEdge(source, idValue(edge, nb.options))
+		return
+	}
+
+	target, ok := document.Map()["target"]
+	if ok && target != nil {
+		nb.addEdge(source, idValue(target, nb.options))
+		return
+	}
+
+	// $graphLookup embeds every descendant, not just the children, so the
+	// parents of embedded nodes give their edges when they are known
+	if id, ok := nb.processNode(document); ok && nb.mapping.ParentField == "" {
+		nb.addEdge(source, id)
+	}
+}
+
+func (nb *NodeGraphBuilder) node(id string, values primitive.M) primitive.D {
+
+	node := primitive.D{{Key: "id", Value: id}}
+	if nb.mapping.TitleField != "" {
+		node = append(node, primitive.E{Key: "title", Value: optionalString(values, nb.mapping.TitleField, nb.options)})
+	}
+	if nb.mapping.SubtitleField != "" {
+		node = append(node, primitive.E{Key: "subTitle", Value: optionalString(values, nb.mapping.SubtitleField, nb.options)})
+	}
+	if nb.mapping.MainStatField != "" {
+		node = append(node, primitive.E{Key: "mainStat", Value: statValue(values[nb.mapping.MainStatField], nb.options)})
+	}
+	if nb.mapping.SecondaryStatField != "" {
+		node = append(node, primitive.E{Key: "secondaryStat", Value: statValue(values[nb.mapping.SecondaryStatField], nb.options)})
+	}
+	return node
+}
+
+func (nb *NodeGraphBuilder) addEdge(source string, target string) {
+
+	edge := [2]string{source, target}
+	if nb.edgeIds[edge] {
+		return
+	}
+
+	// ids joined from the source and target are ambiguous, a-b-c could be
+	// a to b-c or a-b to c, so the row number is the id
+	nb.edgeIds[edge] = true
+	nb.endpoints = append(nb.endpoints, source, target)
+	nb.edges.ProcessRecord(primitive.D{
+		{Key: "id", Value: strconv.Itoa(len(nb.edgeIds) - 1)},
+		{Key: "source", Value: source},
+		{Key: "target", Value: target},
+	})
+}
+
+func statValue(value interface{}, options field.Options) interface{} {
+
+	if value == nil {
+		return nil
+	}
+
+	result, ok := field.FloatValue(value)
+	if ok {
+		return result
+	}
+	return field.StringValueWithOptions(value, options)
+}
+
+func asArray(value interface{}) primitive.A {
+
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case primitive.A:
+		return value
+	default:
+		return primitive.A{value}
+	}
+}
@@ -0,0 +1,111 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNodeGraphBuilder(t *testing.T) {
+
+	meta := &data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph}
+
+	var tests = []struct {
+		mapping NodeGraphMapping
+		records []primitive.D
+		want    []*data.Frame
+	}{
+		//Parent references with stats, unknown parents become nodes
+		{NodeGraphMapping{TitleField: "name", MainStatField: "rps", SecondaryStatField: "status", ParentField: "callers"},
+			[]primitive.D{
+				{{Key: "_id", Value: "api"}, {Key: "name", Value: "API"}, {Key: "rps", Value: int32(10)}, {Key: "status", Value: "ok"},
+					{Key: "callers", Value: "web"}},
+				{{Key: "_id", Value: "db"}, {Key: "name", Value: "DB"}, {Key: "rps", Value: 2.5},
+					{Key: "callers", Value: primitive.A{"api", "web"}}},
+			},
+			[]*data.Frame{
+				data.NewFrame("nodes",
+					data.NewField("id", nil, []string{"api", "db", "web"}),
+					data.NewField("title", nil, []string{"API", "DB", "web"}),
+					data.NewField("mainStat", nil, []*float64{float64Ptr(10), float64Ptr(2.5), nil}),
+					data.NewField("secondaryStat", nil, []*string{stringPtr("ok"), nil, nil}),
+				).SetMeta(meta),
+				data.NewFrame("edges",
+					data.NewField("id", nil, []string{"0", "1", "2"}),
+					data.NewField("source", nil, []string{"web", "api", "web"}),
+					data.NewField("target", nil, []string{"api", "db", "db"}),
+				).SetMeta(meta),
+			}},
+
+		//Embedded edges and $graphLookup nodes
+		{NodeGraphMapping{IdField: "name", EdgesField: "connections"},
+			[]primitive.D{
+				{{Key: "name", Value: "a"}, {Key: "connections", Value: primitive.A{
+					"b",
+					primitive.D{{Key: "target", Value: "c"}},
+					primitive.D{{Key: "name", Value: "d"}, {Key: "connections", Value: primitive.A{"a"}}},
+				}}},
+			},
+			[]*data.Frame{
+				data.NewFrame("nodes",
+					data.NewField("id", nil, []string{"a", "d", "b", "c"}),
+				).SetMeta(meta),
+				data.NewFrame("edges",
+					data.NewField("id", nil, []string{"0", "1", "2", "3"}),
+					data.NewField("source", nil, []string{"a", "a", "d", "a"}),
+					data.NewField("target", nil, []string{"b", "c", "a", "d"}),
+				).SetMeta(meta),
+			}},
+
+		//$graphLookup descendants only give their parent edges
+		{NodeGraphMapping{ParentField: "reportsTo", EdgesField: "reports"},
+			[]primitive.D{
+				{{Key: "_id", Value: "a"}, {Key: "reports", Value: primitive.A{
+					primitive.D{{Key: "_id", Value: "b"}, {Key: "reportsTo", Value: "a"}},
+					primitive.D{{Key: "_id", Value: "c"}, {Key: "reportsTo", Value: "b"}},
+				}}},
+			},
+			[]*data.Frame{
+				data.NewFrame("nodes",
+					data.NewField("id", nil, []string{"a", "b", "c"}),
+				).SetMeta(meta),
+				data.NewFrame("edges",
+					data.NewField("id", nil, []string{"0", "1"}),
+					data.NewField("source", nil, []string{"a", "b"}),
+					data.NewField("target", nil, []string{"b", "c"}),
+				).SetMeta(meta),
+			}},
+
+		//Edges whose joined ids would collide
+		{NodeGraphMapping{EdgesField: "to"},
+			[]primitive.D{
+				{{Key: "_id", Value: "web"}, {Key: "to", Value: primitive.A{"api-db", "api-db"}}},
+				{{Key: "_id", Value: "web-api"}, {Key: "to", Value: "db"}},
+			},
+			[]*data.Frame{
+				data.NewFrame("nodes",
+					data.NewField("id", nil, []string{"web", "web-api", "api-db", "db"}),
+				).SetMeta(meta),
+				data.NewFrame("edges",
+					data.NewField("id", nil, []string{"0", "1"}),
+					data.NewField("source", nil, []string{"web", "web-api"}),
+					data.NewField("target", nil, []string{"api-db", "db"}),
+				).SetMeta(meta),
+			}},
+	}
+
+	for _, test := range tests {
+
+		nb := NewNodeGraphBuilder(test.mapping, field.Options{})
+		for _, record := range test.records {
+			nb.ProcessRecord(record)
+		}
+
+		if got := nb.Build(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("NodeGraphBuilder.Build(%v) = %v", test.records, got)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// PivotOptions name the key and value fields of a pivot.
+type PivotOptions struct {
+	KeyField    string
+	ValueField  string
+	Aggregation Aggregation
+}
+
+// Pivot turns each distinct value of the key field into a field holding the
+// values of the value field. The rows are grouped by the remaining fields,
+// in the order each group is first seen, and numeric values of the same
+// group and key are aggregated, defaulting to the last value. Other values
+// keep their last value. Rows with a null key are dropped. Frames without
+// rows are returned unchanged.
+func Pivot(frame *data.Frame, options PivotOptions) (*data.Frame, error) {
+
+	if options.KeyField == "" || options.ValueField == "" {
+		return nil, errors.New("pivoting requires a key and a value field")
+	}
+
+	if frame.Rows() == 0 {
+		return frame, nil
+	}
+
+	keyField := fieldByName(frame, options.KeyField)
+	if keyField == nil {
+		return nil, fmt.Errorf("the pivot key field '%s' does not exist", options.KeyField)
+	}
+
+	valueField := fieldByName(frame, options.ValueField)
+	if valueField == nil {
+		return nil, fmt.Errorf("the pivot value field '%s' does not exist", options.ValueField)
+	}
+
+	groupFields := make([]*data.Field, 0, len(frame.Fields))
+	for _, field := range frame.Fields {
+		if field != keyField && field != valueField {
+			groupFields = append(groupFields, field)
+		}
+	}
+
+	// the group of each row and the first row of each group
+	groups := make(map[string]int)
+	groupRows := make([]int, 0)
+	rowGroups := make([]int, frame.Rows())
+	keys := make([]string, 0)
+	keyRows := make(map[string][]int)
+	for row := range rowGroups {
+
+		key, ok := keyField.ConcreteAt(row)
+		if !ok {
+			rowGroups[row] = -1
+			continue
+		}
+
+		group := groupKey(groupFields, row)
+		index, ok := groups[group]
+		if !ok {
+			index = len(groupRows)
+			groups[group] = index
+			groupRows = append(groupRows, row)
+		}
+		rowGroups[row] = index
+
+		name := fmt.Sprintf("%v", key)
+		if _, ok := keyRows[name]; !ok {
+			keys = append(keys, name)
+		}
+		keyRows[name] = append(keyRows[name], row)
+	}
+
+	fields := make([]*data.Field, 0, len(groupFields)+len(keys))
+	for _, field := range groupFields {
+		fields = append(fields, selectRows(field, groupRows))
+	}
+
+	resample := ResampleOptions{Aggregation: options.Aggregation}
+	for _, key := range keys {
+
+		// only the rows of the key are aggregated into their group
+		rowSteps := make([]int, frame.Rows())
+		for row := range rowSteps {
+			rowSteps[row] = -1
+		}
+		for _, row := range keyRows[key] {
+			rowSteps[row] = rowGroups[row]
+		}
+
+		var result *data.Field
+		if valueField.Type().Numeric() {
+			result = resampleNumbers(valueField, rowSteps, len(groupRows), resample)
+		} else {
+			result = resampleValues(valueField, rowSteps, len(groupRows), "")
+		}
+		result.Name = key
+		fields = append(fields, result)
+	}
+
+	result := data.NewFrame(frame.Name, fields...)
+	result.Meta = frame.Meta
+	return result, nil
+}
+
+// groupKey returns a key identifying the values of the fields in a row,
+// distinguishing null values from empty strings.
+func groupKey(fields []*data.Field, row int) string {
+
+	var key strings.Builder
+	for _, field := range fields {
+		if value, ok := field.ConcreteAt(row); ok {
+			fmt.Fprintf(&key, "+%v\x00", value)
+		} else {
+			key.WriteString("-\x00")
+		}
+	}
+	return key.String()
+}
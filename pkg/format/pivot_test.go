@@ -0,0 +1,84 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestPivot(t *testing.T) {
+
+	t1 := time.Unix(10, 0)
+	t2 := time.Unix(20, 0)
+	meta := &data.FrameMeta{Notices: []data.Notice{{Text: "notice"}}}
+
+	input := func() *data.Frame {
+		return data.NewFrame("response",
+			data.NewField("ts", nil, []time.Time{t1, t1, t2, t1, t2}),
+			data.NewField("name", nil, []*string{stringPtr("cpu"), stringPtr("mem"), stringPtr("cpu"), stringPtr("cpu"), nil}),
+			data.NewField("value", nil, []float64{0.4, 2, 0.6, 0.2, 9})).SetMeta(meta)
+	}
+
+	var tests = []struct {
+		input   *data.Frame
+		options PivotOptions
+		want    *data.Frame
+		err     string
+	}{
+		//Empty frame is unchanged
+		{data.NewFrame("response"), PivotOptions{KeyField: "name", ValueField: "value"},
+			data.NewFrame("response"), ""},
+
+		//Empty frame with typed fields is unchanged
+		{data.NewFrame("response",
+			data.NewField("ts", nil, []*time.Time{}),
+			data.NewField("name", nil, []*string{})), PivotOptions{KeyField: "name", ValueField: "value"},
+			data.NewFrame("response",
+				data.NewField("ts", nil, []*time.Time{}),
+				data.NewField("name", nil, []*string{})), ""},
+
+		//Last value by default, null keys are dropped
+		{input(), PivotOptions{KeyField: "name", ValueField: "value"},
+			data.NewFrame("response",
+				data.NewField("ts", nil, []time.Time{t1, t2}),
+				data.NewField("cpu", nil, []*float64{float64Ptr(0.2), float64Ptr(0.6)}),
+				data.NewField("mem", nil, []*float64{float64Ptr(2), nil})).SetMeta(meta), ""},
+
+		//Sum on collision
+		{input(), PivotOptions{KeyField: "name", ValueField: "value", Aggregation: AggregateSum},
+			data.NewFrame("response",
+				data.NewField("ts", nil, []time.Time{t1, t2}),
+				data.NewField("cpu", nil, []*float64{float64Ptr(0.6000000000000001), float64Ptr(0.6)}),
+				data.NewField("mem", nil, []*float64{float64Ptr(2), nil})).SetMeta(meta), ""},
+
+		//String values
+		{data.NewFrame("response",
+			data.NewField("host", nil, []string{"a", "a", "b"}),
+			data.NewField("key", nil, []int32{1, 2, 1}),
+			data.NewField("status", nil, []string{"up", "down", "up"})),
+			PivotOptions{KeyField: "key", ValueField: "status"},
+			data.NewFrame("response",
+				data.NewField("host", nil, []string{"a", "b"}),
+				data.NewField("1", nil, []*string{stringPtr("up"), stringPtr("up")}),
+				data.NewField("2", nil, []*string{stringPtr("down"), nil})), ""},
+
+		//Missing value field
+		{input(), PivotOptions{KeyField: "name"},
+			nil, "pivoting requires a key and a value field"},
+
+		//Unknown key field
+		{input(), PivotOptions{KeyField: "wibble", ValueField: "value"},
+			nil, "the pivot key field 'wibble' does not exist"},
+	}
+
+	for _, test := range tests {
+		got, err := Pivot(test.input, test.options)
+		if err != nil && err.Error() != test.err || err == nil && test.err != "" {
+			t.Errorf("Pivot(%v, %v) error = %v", test.input, test.options, err)
+		} else if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Pivot(%v, %v) = %v", test.input, test.options, got)
+		}
+	}
+}
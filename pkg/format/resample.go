@@ -0,0 +1,240 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+type FillMode string
+
+const (
+	// FillNull leaves steps without values null.
+	FillNull FillMode = "null"
+
+	// FillPrevious repeats the value of the previous step.
+	FillPrevious FillMode = "previous"
+
+	// FillZero sets steps without values to zero.
+	FillZero FillMode = "zero"
+
+	// FillLinear interpolates between the values of the steps either side.
+	FillLinear FillMode = "linear"
+)
+
+func (m FillMode) IsValid() bool {
+	return m == "" || m == FillNull || m == FillPrevious || m == FillZero || m == FillLinear
+}
+
+type Aggregation string
+
+const (
+	AggregateLast Aggregation = "last"
+	AggregateMean Aggregation = "mean"
+	AggregateSum  Aggregation = "sum"
+	AggregateMin  Aggregation = "min"
+	AggregateMax  Aggregation = "max"
+)
+
+func (a Aggregation) IsValid() bool {
+
+	switch a {
+	case "", AggregateLast, AggregateMean, AggregateSum, AggregateMin, AggregateMax:
+		return true
+	default:
+		return false
+	}
+}
+
+// maxResampleSteps limits the size of resampled frames.
+const maxResampleSteps = 1000000
+
+// ResampleOptions control how a frame is aligned to regular steps.
+type ResampleOptions struct {
+	Step        time.Duration
+	From        time.Time
+	To          time.Time
+	Fill        FillMode
+	Aggregation Aggregation
+}
+
+// Resample aligns the rows of a frame to steps of a fixed duration between
+// From and To. The values of each numeric field within a step are
+// aggregated, defaulting to the last value, and steps without values are
+// filled according to the fill mode. Other fields take their last value in
+// each step and are only filled by FillPrevious. The first time field of the
+// frame is replaced by the start of each step. Frames without rows are
+// returned unchanged.
+func Resample(frame *data.Frame, options ResampleOptions) (*data.Frame, error) {
+
+	if frame.Rows() == 0 {
+		return frame, nil
+	}
+
+	if options.Step <= 0 {
+		return nil, errors.New("the resample step must be positive")
+	}
+
+	timeIndex, timeField, _ := findTimeField(frame, "")
+	if timeIndex == -1 {
+		return nil, errors.New("resampling requires a time field")
+	}
+
+	step := int64(options.Step)
+	start := floorDiv(options.From.UnixNano(), step) * step
+	steps := int64(0)
+	if end := options.To.UnixNano(); end >= start {
+		steps = (end-start)/step + 1
+	}
+	if steps > maxResampleSteps {
+		return nil, fmt.Errorf("the resample step %v is too small for the time range", options.Step)
+	}
+
+	// the step of each row, -1 outside the time range
+	rowSteps := make([]int, frame.Rows())
+	for row := range rowSteps {
+		rowSteps[row] = -1
+		t, ok := timeAt(timeField, row)
+		if ok && !t.Before(time.Unix(0, start)) {
+			if i := (t.UnixNano() - start) / step; i < steps {
+				rowSteps[row] = int(i)
+			}
+		}
+	}
+
+	times := make([]time.Time, steps)
+	for i := range times {
+		times[i] = time.Unix(0, start+int64(i)*step)
+	}
+
+	config := data.FieldConfig{}
+	if timeField.Config != nil {
+		config = *timeField.Config
+	}
+	config.Interval = float64(options.Step) / float64(time.Millisecond)
+
+	fields := make([]*data.Field, 0, len(frame.Fields))
+	for i, field := range frame.Fields {
+
+		var result *data.Field
+		switch {
+		case i == timeIndex:
+			result = data.NewField(field.Name, field.Labels, times).SetConfig(&config)
+		case field.Type().Numeric():
+			result = resampleNumbers(field, rowSteps, int(steps), options)
+		default:
+			result = resampleValues(field, rowSteps, int(steps), options.Fill)
+		}
+		fields = append(fields, result)
+	}
+
+	result := data.NewFrame(frame.Name, fields...)
+	result.Meta = frame.Meta
+	return result, nil
+}
+
+func resampleNumbers(field *data.Field, rowSteps []int, steps int, options ResampleOptions) *data.Field {
+
+	values := make([]*float64, steps)
+	counts := make([]int, steps)
+	for row, i := range rowSteps {
+
+		value, err := field.FloatAt(row)
+		if i == -1 || err != nil || math.IsNaN(value) {
+			continue
+		}
+
+		counts[i]++
+		if values[i] == nil {
+			values[i] = &value
+			continue
+		}
+
+		current := values[i]
+		switch options.Aggregation {
+		case AggregateSum, AggregateMean:
+			*current += value
+		case AggregateMin:
+			*current = math.Min(*current, value)
+		case AggregateMax:
+			*current = math.Max(*current, value)
+		default:
+			*current = value
+		}
+	}
+
+	if options.Aggregation == AggregateMean {
+		for i, value := range values {
+			if value != nil {
+				*value /= float64(counts[i])
+			}
+		}
+	}
+
+	fillNumbers(values, options.Fill)
+	return data.NewField(field.Name, field.Labels, values).SetConfig(field.Config)
+}
+
+func fillNumbers(values []*float64, fill FillMode) {
+
+	previous := -1
+	for i, value := range values {
+
+		if value != nil {
+			if fill == FillLinear && previous != -1 && previous < i-1 {
+				from, to := *values[previous], *value
+				for j := previous + 1; j < i; j++ {
+					v := from + (to-from)*float64(j-previous)/float64(i-previous)
+					values[j] = &v
+				}
+			}
+			previous = i
+			continue
+		}
+
+		switch {
+		case fill == FillZero:
+			zero := float64(0)
+			values[i] = &zero
+		case fill == FillPrevious && previous != -1:
+			v := *values[previous]
+			values[i] = &v
+		}
+	}
+}
+
+func resampleValues(field *data.Field, rowSteps []int, steps int, fill FillMode) *data.Field {
+
+	result := data.NewFieldFromFieldType(field.Type().NullableType(), steps)
+	result.Name = field.Name
+	result.Labels = field.Labels
+	result.Config = field.Config
+
+	for row, i := range rowSteps {
+		if value, ok := field.ConcreteAt(row); i != -1 && ok {
+			result.SetConcrete(i, value)
+		}
+	}
+
+	if fill == FillPrevious {
+		for i := 1; i < steps; i++ {
+			if _, ok := result.ConcreteAt(i); !ok {
+				result.Set(i, result.CopyAt(i-1))
+			}
+		}
+	}
+	return result
+}
+
+// floorDiv divides rounding towards negative infinity.
+func floorDiv(a int64, b int64) int64 {
+
+	result := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		result--
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestResample(t *testing.T) {
+
+	t0 := time.Unix(0, 0)
+	t1 := time.Unix(10, 0)
+	t2 := time.Unix(20, 0)
+	t3 := time.Unix(30, 0)
+	before := time.Unix(-10, 0)
+	config := &data.FieldConfig{Interval: 10000}
+	meta := &data.FrameMeta{Notices: []data.Notice{{Text: "notice"}}}
+
+	input := func() *data.Frame {
+		return data.NewFrame("response",
+			data.NewField("time", nil, []time.Time{before, t0, time.Unix(5, 0), t3}),
+			data.NewField("value", nil, []int32{9, 1, 3, 4}),
+			data.NewField("name", nil, []string{"z", "a", "b", "d"})).SetMeta(meta)
+	}
+	times := func() *data.Field {
+		return data.NewField("time", nil, []time.Time{t0, t1, t2, t3}).SetConfig(config)
+	}
+	names := func(values ...*string) *data.Field {
+		return data.NewField("name", nil, values)
+	}
+	options := func(fill FillMode, aggregation Aggregation) ResampleOptions {
+		return ResampleOptions{Step: 10 * time.Second, From: time.Unix(3, 0), To: time.Unix(35, 0),
+			Fill: fill, Aggregation: aggregation}
+	}
+
+	var tests = []struct {
+		input   *data.Frame
+		options ResampleOptions
+		want    *data.Frame
+		err     string
+	}{
+		//Empty frame is unchanged
+		{data.NewFrame("response"), options("", ""),
+			data.NewFrame("response"), ""},
+
+		//Last value and null fill by default
+		{input(), options("", ""),
+			data.NewFrame("response", times(),
+				data.NewField("value", nil, []*float64{float64Ptr(3), nil, nil, float64Ptr(4)}),
+				names(stringPtr("b"), nil, nil, stringPtr("d"))).SetMeta(meta), ""},
+
+		//Mean and previous fill
+		{input(), options(FillPrevious, AggregateMean),
+			data.NewFrame("response", times(),
+				data.NewField("value", nil, []*float64{float64Ptr(2), float64Ptr(2), float64Ptr(2), float64Ptr(4)}),
+				names(stringPtr("b"), stringPtr("b"), stringPtr("b"), stringPtr("d"))).SetMeta(meta), ""},
+
+		//Sum and zero fill
+		{input(), options(FillZero, AggregateSum),
+			data.NewFrame("response", times(),
+				data.NewField("value", nil, []*float64{float64Ptr(4), float64Ptr(0), float64Ptr(0), float64Ptr(4)}),
+				names(stringPtr("b"), nil, nil, stringPtr("d"))).SetMeta(meta), ""},
+
+		//Min and linear fill
+		{input(), options(FillLinear, AggregateMin),
+			data.NewFrame("response", times(),
+				data.NewField("value", nil, []*float64{float64Ptr(1), float64Ptr(2), float64Ptr(3), float64Ptr(4)}),
+				names(stringPtr("b"), nil, nil, stringPtr("d"))).SetMeta(meta), ""},
+
+		//Max
+		{input(), options(FillNull, AggregateMax),
+			data.NewFrame("response", times(),
+				data.NewField("value", nil, []*float64{float64Ptr(3), nil, nil, float64Ptr(4)}),
+				names(stringPtr("b"), nil, nil, stringPtr("d"))).SetMeta(meta), ""},
+
+		//No time field
+		{data.NewFrame("response",
+			data.NewField("value", nil, []int32{1})), options("", ""),
+			nil, "resampling requires a time field"},
+
+		//Step that is not positive
+		{input(), ResampleOptions{}, nil, "the resample step must be positive"},
+
+		//Step that is too small
+		{input(), ResampleOptions{Step: time.Nanosecond, From: t0, To: t3},
+			nil, "the resample step 1ns is too small for the time range"},
+	}
+
+	for _, test := range tests {
+		got, err := Resample(test.input, test.options)
+		if err != nil && err.Error() != test.err || err == nil && test.err != "" {
+			t.Errorf("Resample(%v, %v) error = %v", test.input, test.options, err)
+		} else if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Resample(%v, %v) = %v", test.input, test.options, got)
+		}
+	}
+}
+
+func TestFloorDiv(t *testing.T) {
+
+	var tests = []struct {
+		a, b int64
+		want int64
+	}{
+		{7, 2, 3},
+		{-7, 2, -4},
+		{-8, 2, -4},
+		{0, 2, 0},
+	}
+
+	for _, test := range tests {
+		if got := floorDiv(test.a, test.b); got != test.want {
+			t.Errorf("floorDiv(%v, %v) = %v", test.a, test.b, got)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package format
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// FieldNamePlaceholder is replaced by the name of the value field in a
+// display name template.
+const FieldNamePlaceholder = "__field"
+
+var displayNameRegex = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// SplitBySeries returns a frame for each distinct combination of values of
+// the seriesBy fields, in the order they are first seen. The seriesBy fields
+// are removed from the frames and their values become the labels of the
+// numeric fields. When displayName is not empty it is used as a template,
+// with {{name}} placeholders for the labels, to set the display name of each
+// numeric field. The time and other fields are left unchanged.
+func SplitBySeries(frame *data.Frame, seriesBy []string, displayName string) ([]*data.Frame, error) {
+
+	// an empty frame has no series but keeps its fields
+	if len(seriesBy) == 0 || frame.Rows() == 0 {
+		return []*data.Frame{frame}, nil
+	}
+
+	groupFields := make([]*data.Field, len(seriesBy))
+	for i, name := range seriesBy {
+		field := fieldByName(frame, name)
+		if field == nil {
+			return nil, fmt.Errorf("the series by field '%s' does not exist", name)
+		}
+		groupFields[i] = field
+	}
+
+	var keys []string
+	labels := make(map[string]data.Labels)
+	rows := make(map[string][]int)
+	for row := 0; row < frame.Rows(); row++ {
+
+		rowLabels := make(data.Labels, len(groupFields))
+		for _, field := range groupFields {
+			rowLabels[field.Name] = labelValue(field, row)
+		}
+
+		key := rowLabels.String()
+		if _, ok := rows[key]; !ok {
+			keys = append(keys, key)
+			labels[key] = rowLabels
+		}
+		rows[key] = append(rows[key], row)
+	}
+
+	frames := make([]*data.Frame, 0, len(keys))
+	for _, key := range keys {
+
+		fields := make([]*data.Field, 0, len(frame.Fields))
+		for _, field := range frame.Fields {
+
+			if contains(seriesBy, field.Name) {
+				continue
+			}
+
+			series := selectRows(field, rows[key])
+			if !field.Type().Numeric() {
+				fields = append(fields, series)
+				continue
+			}
+
+			series.Labels = labels[key].Copy()
+			if displayName != "" {
+				config := data.FieldConfig{}
+				if field.Config != nil {
+					config = *field.Config
+				}
+				config.DisplayNameFromDS = expandDisplayName(displayName, field.Name, series.Labels)
+				series.Config = &config
+			}
+			fields = append(fields, series)
+		}
+
+		result := data.NewFrame(key, fields...)
+		result.Meta = frame.Meta
+		frames = append(frames, result)
+	}
+	return frames, nil
+}
+
+func labelValue(field *data.Field, row int) string {
+
+	value, ok := field.ConcreteAt(row)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func expandDisplayName(template string, fieldName string, labels data.Labels) string {
+
+	return displayNameRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+
+		name := displayNameRegex.FindStringSubmatch(placeholder)[1]
+		if name == FieldNamePlaceholder {
+			return fieldName
+		}
+
+		value, ok := labels[name]
+		if !ok {
+			return placeholder
+		}
+		return value
+	})
+}
+
+func contains(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
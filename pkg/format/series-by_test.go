@@ -0,0 +1,82 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestSplitBySeries(t *testing.T) {
+
+	t1 := time.Unix(10, 0)
+	t2 := time.Unix(20, 0)
+	env := "prod"
+
+	input := data.NewFrame("response",
+		data.NewField("time", nil, []time.Time{t1, t1, t2}),
+		data.NewField("hostname", nil, []string{"a", "b", "a"}),
+		data.NewField("env", nil, []*string{&env, nil, &env}),
+		data.NewField("cpu", nil, []float64{1, 2, 3}))
+
+	var tests = []struct {
+		seriesBy    []string
+		displayName string
+		want        []*data.Frame
+		err         string
+	}{
+		//No series by fields
+		{nil, "",
+			[]*data.Frame{input}, ""},
+
+		//Unknown series by field
+		{[]string{"wibble"}, "",
+			nil, "the series by field 'wibble' does not exist"},
+
+		//Single series by field
+		{[]string{"hostname"}, "",
+			[]*data.Frame{
+				data.NewFrame("hostname=a",
+					data.NewField("time", nil, []time.Time{t1, t2}),
+					data.NewField("env", nil, []*string{&env, &env}),
+					data.NewField("cpu", data.Labels{"hostname": "a"}, []float64{1, 3})),
+				data.NewFrame("hostname=b",
+					data.NewField("time", nil, []time.Time{t1}),
+					data.NewField("env", nil, []*string{nil}),
+					data.NewField("cpu", data.Labels{"hostname": "b"}, []float64{2})),
+			}, ""},
+
+		//Multiple series by fields with a display name
+		{[]string{"hostname", "env"}, "{{hostname}} {{env}} {{__field}} {{wibble}}",
+			[]*data.Frame{
+				data.NewFrame("env=prod, hostname=a",
+					data.NewField("time", nil, []time.Time{t1, t2}),
+					data.NewField("cpu", data.Labels{"hostname": "a", "env": "prod"}, []float64{1, 3}).
+						SetConfig(&data.FieldConfig{DisplayNameFromDS: "a prod cpu {{wibble}}"})),
+				data.NewFrame("env=, hostname=b",
+					data.NewField("time", nil, []time.Time{t1}),
+					data.NewField("cpu", data.Labels{"hostname": "b", "env": ""}, []float64{2}).
+						SetConfig(&data.FieldConfig{DisplayNameFromDS: "b  cpu {{wibble}}"})),
+			}, ""},
+	}
+
+	for _, test := range tests {
+		got, err := SplitBySeries(input, test.seriesBy, test.displayName)
+		if err != nil && err.Error() != test.err || err == nil && test.err != "" {
+			t.Errorf("SplitBySeries(%v) error = %v", test.seriesBy, err)
+		} else if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitBySeries(%v) = %v", test.seriesBy, got)
+		}
+	}
+}
+
+func TestSplitBySeriesEmpty(t *testing.T) {
+
+	input := data.NewFrame("response", data.NewField("hostname", nil, []*string{}))
+	want := []*data.Frame{input}
+
+	if got, err := SplitBySeries(input, []string{"hostname"}, ""); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitBySeries() = %v, %v", got, err)
+	}
+}
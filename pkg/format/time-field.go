@@ -0,0 +1,114 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// epochSecondsLimit separates epoch times in seconds from epoch times in
+// milliseconds, it is in the year 5138 in seconds and 1973 in milliseconds.
+const epochSecondsLimit = 1e11
+
+// SortByTime moves the time field of a frame first and sorts the rows
+// ascending by it, rows with a null time come last. The time field is the
+// named field, which is converted from epoch seconds or milliseconds when it
+// is numeric, or the first time field when name is empty. Frames without a
+// time field or rows are returned unchanged.
+func SortByTime(frame *data.Frame, name string) (*data.Frame, error) {
+
+	if frame.Rows() == 0 {
+		return frame, nil
+	}
+
+	timeIndex, timeField, err := findTimeField(frame, name)
+	if err != nil || timeIndex == -1 {
+		return frame, err
+	}
+
+	rows := sortedTimeRows(timeField, true)
+	for row := 0; row < timeField.Len(); row++ {
+		if _, ok := timeAt(timeField, row); !ok {
+			rows = append(rows, row)
+		}
+	}
+
+	fields := []*data.Field{selectRows(timeField, rows)}
+	for i, field := range frame.Fields {
+		if i != timeIndex {
+			fields = append(fields, selectRows(field, rows))
+		}
+	}
+
+	result := data.NewFrame(frame.Name, fields...)
+	result.Meta = frame.Meta
+	return result, nil
+}
+
+// findTimeField returns the index of the time field of a frame and the field
+// converted to a time field, the index is -1 when there is no time field.
+func findTimeField(frame *data.Frame, name string) (int, *data.Field, error) {
+
+	if name == "" {
+		for i, field := range frame.Fields {
+			if isTime(field.Type()) {
+				return i, field, nil
+			}
+		}
+		return -1, nil, nil
+	}
+
+	for i, field := range frame.Fields {
+
+		if field.Name != name {
+			continue
+		}
+
+		switch {
+		case isTime(field.Type()):
+			return i, field, nil
+		case field.Type().Numeric():
+			return i, epochTimeField(field), nil
+		default:
+			return -1, nil, fmt.Errorf("the time field '%s' is not a date or a number", name)
+		}
+	}
+	return -1, nil, fmt.Errorf("the time field '%s' does not exist", name)
+}
+
+// epochTimeField converts a numeric field of epoch times in seconds or
+// milliseconds into a nullable time field.
+func epochTimeField(field *data.Field) *data.Field {
+
+	result := data.NewFieldFromFieldType(data.FieldTypeNullableTime, field.Len())
+	result.Name = field.Name
+	result.Labels = field.Labels
+	result.Config = field.Config
+	for i := 0; i < field.Len(); i++ {
+
+		epoch, err := field.FloatAt(i)
+		if err != nil || math.IsNaN(epoch) || math.IsInf(epoch, 0) {
+			continue
+		}
+
+		t := guessEpochTime(epoch)
+		result.Set(i, &t)
+	}
+	return result
+}
+
+// guessEpochTime converts an epoch time in seconds or milliseconds, telling
+// them apart by their size, without losing the precision of whole
+// milliseconds.
+func guessEpochTime(epoch float64) time.Time {
+
+	whole, fraction := math.Modf(epoch)
+	if math.Abs(epoch) < epochSecondsLimit {
+		return time.Unix(int64(whole), int64(math.Round(fraction*1e9)))
+	}
+
+	millis := int64(whole)
+	return time.Unix(millis/1000, millis%1000*1e6+int64(math.Round(fraction*1e6)))
+}
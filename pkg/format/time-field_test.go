@@ -0,0 +1,101 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestSortByTime(t *testing.T) {
+
+	t1 := time.Unix(10, 0)
+	t2 := time.Unix(20, 0)
+	t3 := time.Unix(1620586358, 123000000)
+	t4 := time.Unix(1620586368, 0)
+	meta := &data.FrameMeta{Notices: []data.Notice{{Text: "notice"}}}
+
+	var tests = []struct {
+		input     *data.Frame
+		timeField string
+		want      *data.Frame
+		err       string
+	}{
+		//Empty frame is unchanged
+		{data.NewFrame("response"), "wibble",
+			data.NewFrame("response"), ""},
+
+		//No time field is unchanged
+		{data.NewFrame("response",
+			data.NewField("value", nil, []int32{2, 1})), "",
+			data.NewFrame("response",
+				data.NewField("value", nil, []int32{2, 1})), ""},
+
+		//First time field moved first with null times last
+		{data.NewFrame("response",
+			data.NewField("value", nil, []int32{3, 2, 1}),
+			data.NewField("time", nil, []*time.Time{nil, &t2, &t1})).SetMeta(meta), "",
+			data.NewFrame("response",
+				data.NewField("time", nil, []*time.Time{&t1, &t2, nil}),
+				data.NewField("value", nil, []int32{1, 2, 3})).SetMeta(meta), ""},
+
+		//Named epoch seconds
+		{data.NewFrame("response",
+			data.NewField("other", nil, []time.Time{t1, t2}),
+			data.NewField("ts", nil, []*int32{int32Ptr(20), nil})), "ts",
+			data.NewFrame("response",
+				data.NewField("ts", nil, []*time.Time{&t2, nil}),
+				data.NewField("other", nil, []time.Time{t1, t2})), ""},
+
+		//Named epoch milliseconds
+		{data.NewFrame("response",
+			data.NewField("ts", nil, []float64{1620586368000, 1620586358123})), "ts",
+			data.NewFrame("response",
+				data.NewField("ts", nil, []*time.Time{&t3, &t4})), ""},
+
+		//Named field that is not a time
+		{data.NewFrame("response",
+			data.NewField("ts", nil, []string{"a"})), "ts",
+			nil, "the time field 'ts' is not a date or a number"},
+	}
+
+	for _, test := range tests {
+		got, err := SortByTime(test.input, test.timeField)
+		if err != nil && err.Error() != test.err || err == nil && test.err != "" {
+			t.Errorf("SortByTime(%v, %q) error = %v", test.input, test.timeField, err)
+		} else if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SortByTime(%v, %q) = %v", test.input, test.timeField, got)
+		}
+	}
+}
+
+func int32Ptr(value int32) *int32 {
+	return &value
+}
+
+func TestEpochTime(t *testing.T) {
+
+	var tests = []struct {
+		epoch float64
+		want  time.Time
+	}{
+		//Seconds
+		{1620586358, time.Unix(1620586358, 0)},
+
+		//Fractional seconds
+		{1620586358.25, time.Unix(1620586358, 250000000)},
+
+		//Milliseconds
+		{1620586358123, time.Unix(1620586358, 123000000)},
+
+		//Before the epoch
+		{-10, time.Unix(-10, 0)},
+	}
+
+	for _, test := range tests {
+		if got := guessEpochTime(test.epoch); !got.Equal(test.want) {
+			t.Errorf("guessEpochTime(%v) = %v", test.epoch, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// ToTimeSeries converts a table frame into a wide time series frame. The
+// time field, found as by SortByTime, becomes the time index and the rows are
+// sorted by it. Each numeric field becomes a series; when string or bool
+// fields are present the frame is treated as a long series and they become
+// the labels of the series. Frames without rows are returned unchanged.
+func ToTimeSeries(frame *data.Frame, timeFieldName string) (*data.Frame, error) {
+
+	if frame.Rows() == 0 {
+		return frame, nil
+	}
+
+	timeIndex, timeField, err := findTimeField(frame, timeFieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeIndex == -1 {
+		return nil, errors.New("the time_series format requires a time field")
+	}
+
+	rows := sortedTimeRows(timeField, true)
+	timeField = selectRows(timeField, rows)
+	if timeField.Nullable() {
+		timeField = nonNullableTime(timeField)
+	}
+
+	fields := []*data.Field{timeField}
+	values := 0
+	dimensions := 0
+	for i, field := range frame.Fields {
+
+		switch {
+		case i == timeIndex:
+		case field.Type().Numeric():
+			fields = append(fields, selectRows(field, rows))
+			values++
+		case isDimension(field.Type()):
+			fields = append(fields, dimensionField(field, rows))
+			dimensions++
+		}
+	}
+
+	if values == 0 {
+		return nil, errors.New("the time_series format requires a numeric field")
+	}
+
+	meta := data.FrameMeta{}
+	if frame.Meta != nil {
+		meta = *frame.Meta
+	}
+	meta.Type = data.FrameTypeTimeSeriesWide
+
+	result := data.NewFrame(frame.Name, fields...).SetMeta(&meta)
+
+	if dimensions > 0 && len(rows) > 0 {
+		wide, err := data.LongToWide(result, &data.FillMissing{Mode: data.FillModeNull})
+		if err != nil {
+			return nil, err
+		}
+		keepLabelsAndConfig(result, wide)
+		return wide, nil
+	}
+	return result, nil
+}
+
+// keepLabelsAndConfig copies the labels and config of the value fields of a
+// long frame, which are dropped by data.LongToWide, to the fields of the wide
+// frame.
+func keepLabelsAndConfig(long *data.Frame, wide *data.Frame) {
+
+	for _, field := range wide.Fields[1:] {
+
+		source := fieldByName(long, field.Name)
+		if source == nil {
+			continue
+		}
+
+		for key, value := range source.Labels {
+			if _, ok := field.Labels[key]; !ok {
+				if field.Labels == nil {
+					field.Labels = make(data.Labels)
+				}
+				field.Labels[key] = value
+			}
+		}
+		field.Config = source.Config
+	}
+}
+
+func nonNullableTime(field *data.Field) *data.Field {
+
+	result := data.NewFieldFromFieldType(data.FieldTypeTime, field.Len())
+	result.Name = field.Name
+	result.Labels = field.Labels
+	result.Config = field.Config
+	for i := 0; i < field.Len(); i++ {
+		t, _ := timeAt(field, i)
+		result.Set(i, t)
+	}
+	return result
+}
+
+// dimensionField returns the given rows of a string or bool field as a non
+// nullable string field suitable for use as labels, nulls become "".
+func dimensionField(field *data.Field, rows []int) *data.Field {
+
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		value, ok := field.ConcreteAt(row)
+		if ok {
+			values[i] = fmt.Sprintf("%v", value)
+		}
+	}
+	return data.NewField(field.Name, field.Labels, values)
+}
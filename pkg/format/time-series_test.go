@@ -0,0 +1,120 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestToTimeSeries(t *testing.T) {
+
+	t1 := time.Unix(10, 0)
+	t2 := time.Unix(20, 0)
+	t3 := time.Unix(30, 0)
+	wideMeta := &data.FrameMeta{Type: data.FrameTypeTimeSeriesWide}
+
+	var tests = []struct {
+		input *data.Frame
+		want  *data.Frame
+		err   string
+	}{
+		//Empty frame is unchanged
+		{data.NewFrame("response"),
+			data.NewFrame("response"), ""},
+
+		//No time field
+		{data.NewFrame("response",
+			data.NewField("value", nil, []int32{1})),
+			nil, "the time_series format requires a time field"},
+
+		//No numeric field
+		{data.NewFrame("response",
+			data.NewField("time", nil, []time.Time{t1})),
+			nil, "the time_series format requires a numeric field"},
+
+		//Wide series sorted by time with the time field first
+		{data.NewFrame("response",
+			data.NewField("value", nil, []int32{3, 1, 2}),
+			data.NewField("time", nil, []time.Time{t3, t1, t2}),
+			data.NewField("other", nil, []time.Time{t1, t2, t3})),
+			data.NewFrame("response",
+				data.NewField("time", nil, []time.Time{t1, t2, t3}),
+				data.NewField("value", nil, []int32{1, 2, 3})).SetMeta(wideMeta), ""},
+
+		//Null times are dropped
+		{data.NewFrame("response",
+			data.NewField("time", nil, []*time.Time{&t2, nil, &t1}),
+			data.NewField("value", nil, []float64{2, 0, 1})),
+			data.NewFrame("response",
+				data.NewField("time", nil, []time.Time{t1, t2}),
+				data.NewField("value", nil, []float64{1, 2})).SetMeta(wideMeta), ""},
+
+		//Long series converted to wide with labels
+		{data.NewFrame("response",
+			data.NewField("time", nil, []time.Time{t2, t1, t1, t2}),
+			data.NewField("host", nil, []string{"a", "a", "b", "b"}),
+			data.NewField("value", nil, []float64{2, 1, 3, 4})),
+			data.NewFrame("response",
+				data.NewField("time", nil, []time.Time{t1, t2}),
+				data.NewField("value", data.Labels{"host": "a"}, []*float64{float64Ptr(1), float64Ptr(2)}),
+				data.NewField("value", data.Labels{"host": "b"}, []*float64{float64Ptr(3), float64Ptr(4)})).SetMeta(wideMeta), ""},
+	}
+
+	for _, test := range tests {
+		got, err := ToTimeSeries(test.input, "")
+		if err != nil && err.Error() != test.err || err == nil && test.err != "" {
+			t.Errorf("ToTimeSeries(%v) error = %v", test.input, err)
+		} else if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ToTimeSeries(%v) = %v", test.input, got)
+		}
+	}
+}
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+func TestToTimeSeriesTimeField(t *testing.T) {
+
+	t1 := time.Unix(10, 0)
+	t2 := time.Unix(20, 0)
+	wideMeta := &data.FrameMeta{Type: data.FrameTypeTimeSeriesWide}
+
+	input := data.NewFrame("response",
+		data.NewField("created", nil, []time.Time{t2, t1}),
+		data.NewField("ts", nil, []int64{10, 20}),
+		data.NewField("value", nil, []float64{1, 2}))
+
+	var tests = []struct {
+		timeField string
+		want      *data.Frame
+		err       string
+	}{
+		//First time field by default
+		{"",
+			data.NewFrame("response",
+				data.NewField("created", nil, []time.Time{t1, t2}),
+				data.NewField("ts", nil, []int64{20, 10}),
+				data.NewField("value", nil, []float64{2, 1})).SetMeta(wideMeta), ""},
+
+		//Named epoch time field
+		{"ts",
+			data.NewFrame("response",
+				data.NewField("ts", nil, []time.Time{t1, t2}),
+				data.NewField("value", nil, []float64{1, 2})).SetMeta(wideMeta), ""},
+
+		//Unknown time field
+		{"wibble", nil, "the time field 'wibble' does not exist"},
+	}
+
+	for _, test := range tests {
+		got, err := ToTimeSeries(input, test.timeField)
+		if err != nil && err.Error() != test.err || err == nil && test.err != "" {
+			t.Errorf("ToTimeSeries(%q) error = %v", test.timeField, err)
+		} else if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ToTimeSeries(%q) = %v", test.timeField, got)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TraceMapping names the document fields that hold each part of a span,
+// empty names default to the field names of the trace frame.
+type TraceMapping struct {
+	TraceIDField       string
+	SpanIDField        string
+	ParentSpanIDField  string
+	ServiceNameField   string
+	OperationNameField string
+	StartTimeField     string
+	DurationField      string
+	TagsField          string
+
+	// DurationUnit is the unit of numeric durations: ns, us, ms or s,
+	// defaults to ms.
+	DurationUnit string
+}
+
+var durationUnits = map[string]float64{
+	"":   1,
+	"ns": 1e-6,
+	"us": 1e-3,
+	"ms": 1,
+	"s":  1e3,
+}
+
+// TraceBuilder converts span documents into a frame for the trace view with
+// the start time and duration in milliseconds and the tags flattened into a
+// list of key/value pairs.
+type TraceBuilder struct {
+	mapping TraceMapping
+	scale   float64
+	options field.Options
+	fields  *field.FieldBuilder
+}
+
+func NewTraceBuilder(mapping TraceMapping, options field.Options) (*TraceBuilder, error) {
+
+	scale, ok := durationUnits[mapping.DurationUnit]
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a valid duration unit", mapping.DurationUnit)
+	}
+
+	mapping.TraceIDField = defaultName(mapping.TraceIDField, "traceId")
+	mapping.SpanIDField = defaultName(mapping.SpanIDField, "spanId")
+	mapping.ParentSpanIDField = defaultName(mapping.ParentSpanIDField, "parentSpanId")
+	mapping.ServiceNameField = defaultName(mapping.ServiceNameField, "serviceName")
+	mapping.OperationNameField = defaultName(mapping.OperationNameField, "operationName")
+	mapping.StartTimeField = defaultName(mapping.StartTimeField, "startTime")
+	mapping.DurationField = defaultName(mapping.DurationField, "duration")
+	mapping.TagsField = defaultName(mapping.TagsField, "tags")
+
+	options = valueOptions(options)
+	return &TraceBuilder{
+		mapping: mapping,
+		scale:   scale,
+		options: options,
+		fields:  field.NewFieldBuilderWithOptions(8, options),
+	}, nil
+}
+
+func (tb *TraceBuilder) ProcessRecord(record primitive.D) {
+
+	values := record.Map()
+	tags := make([]keyValue, 0)
+	flattenTags("", values[tb.mapping.TagsField], tb.options, &tags)
+	tagsJSON, _ := json.Marshal(tags)
+
+	var startTime interface{}
+	if value, ok := values[tb.mapping.StartTimeField]; ok {
+		startTime = epochMillis(value)
+	}
+
+	var duration interface{}
+	if value, ok := field.FloatValue(values[tb.mapping.DurationField]); ok {
+		duration = value * tb.scale
+	}
+
+	tb.fields.ProcessRecord(primitive.D{
+		{Key: "traceID", Value: optionalId(values, tb.mapping.TraceIDField, tb.options)},
+		{Key: "spanID", Value: optionalId(values, tb.mapping.SpanIDField, tb.options)},
+		{Key: "parentSpanID", Value: optionalId(values, tb.mapping.ParentSpanIDField, tb.options)},
+		{Key: "serviceName", Value: optionalString(values, tb.mapping.ServiceNameField, tb.options)},
+		{Key: "operationName", Value: optionalString(values, tb.mapping.OperationNameField, tb.options)},
+		{Key: "startTime", Value: startTime},
+		{Key: "duration", Value: duration},
+		{Key: "tags", Value: json.RawMessage(tagsJSON)},
+	})
+}
+
+func (tb *TraceBuilder) Build() *data.Frame {
+
+	frame := data.NewFrame("trace", tb.fields.BuildFields()...)
+	frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTrace}
+	return frame
+}
+
+type keyValue struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// flattenTags appends the tags in value to the list, nested documents are
+// flattened using dotted keys. Tags that are already a list of documents
+// with key and value fields are used as is.
+func flattenTags(prefix string, value interface{}, options field.Options, tags *[]keyValue) {
+
+	switch value := value.(type) {
+
+	case nil:
+
+	case primitive.D:
+		for _, e := range value {
+			flattenTags(joinKey(prefix, e.Key), e.Value, options, tags)
+		}
+
+	case primitive.A:
+		for i, v := range value {
+			key, tagValue, ok := keyValueTag(v)
+			if ok {
+				flattenTags(joinKey(prefix, key), tagValue, options, tags)
+			} else {
+				flattenTags(joinKey(prefix, strconv.Itoa(i)), v, options, tags)
+			}
+		}
+
+	default:
+		*tags = append(*tags, keyValue{Key: prefix, Value: tagValue(value, options)})
+	}
+}
+
+// keyValueTag returns the key and value of a {key: ..., value: ...} tag
+// document.
+func keyValueTag(value interface{}) (string, interface{}, bool) {
+
+	tag, ok := value.(primitive.D)
+	if !ok {
+		return "", nil, false
+	}
+
+	values := tag.Map()
+	key, ok := values["key"].(string)
+	return key, values["value"], ok
+}
+
+func tagValue(value interface{}, options field.Options) interface{} {
+
+	switch value := value.(type) {
+	case bool, int32, int64, float64:
+		return value
+	default:
+		return field.StringValueWithOptions(value, options)
+	}
+}
+
+func joinKey(prefix string, key string) string {
+
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// epochMillis returns a date as milliseconds since the epoch, numbers are
+// assumed to already be in milliseconds.
+func epochMillis(value interface{}) interface{} {
+
+	switch value := value.(type) {
+	case primitive.DateTime:
+		return float64(value)
+	case time.Time:
+		return float64(value.UnixNano()) / float64(time.Millisecond)
+	default:
+		result, ok := field.FloatValue(value)
+		if !ok {
+			return nil
+		}
+		return result
+	}
+}
+
+func optionalId(values primitive.M, name string, options field.Options) interface{} {
+
+	value, ok := values[name]
+	if !ok || value == nil {
+		return nil
+	}
+	return idValue(value, options)
+}
+
+func optionalString(values primitive.M, name string, options field.Options) interface{} {
+
+	value, ok := values[name]
+	if !ok || value == nil {
+		return nil
+	}
+	return field.StringValueWithOptions(value, options)
+}
+
+func defaultName(name string, defaultValue string) string {
+
+	if name == "" {
+		return defaultValue
+	}
+	return name
+}
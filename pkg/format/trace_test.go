@@ -0,0 +1,81 @@
+package format
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTraceBuilder(t *testing.T) {
+
+	start := time.Unix(1620586358, 0)
+	traceMeta := &data.FrameMeta{PreferredVisualization: data.VisTypeTrace}
+
+	var tests = []struct {
+		mapping TraceMapping
+		records []primitive.D
+		want    *data.Frame
+	}{
+		//Default mapping
+		{TraceMapping{},
+			[]primitive.D{
+				{{Key: "traceId", Value: "t1"}, {Key: "spanId", Value: "s1"}, {Key: "serviceName", Value: "api"},
+					{Key: "operationName", Value: "GET"}, {Key: "startTime", Value: primitive.NewDateTimeFromTime(start)},
+					{Key: "duration", Value: int32(12)},
+					{Key: "tags", Value: primitive.D{{Key: "http", Value: primitive.D{{Key: "status", Value: int32(200)}}}, {Key: "error", Value: false}}}},
+				{{Key: "traceId", Value: "t1"}, {Key: "spanId", Value: "s2"}, {Key: "parentSpanId", Value: "s1"}, {Key: "serviceName", Value: "db"},
+					{Key: "operationName", Value: "find"}, {Key: "startTime", Value: int64(1620586358005)},
+					{Key: "duration", Value: 2.5},
+					{Key: "tags", Value: primitive.A{primitive.D{{Key: "key", Value: "db.name"}, {Key: "value", Value: "test"}}}}},
+			},
+			data.NewFrame("trace",
+				data.NewField("traceID", nil, []string{"t1", "t1"}),
+				data.NewField("spanID", nil, []string{"s1", "s2"}),
+				data.NewField("parentSpanID", nil, []*string{nil, stringPtr("s1")}),
+				data.NewField("serviceName", nil, []string{"api", "db"}),
+				data.NewField("operationName", nil, []string{"GET", "find"}),
+				data.NewField("startTime", nil, []float64{1620586358000, 1620586358005}),
+				data.NewField("duration", nil, []float64{12, 2.5}),
+				data.NewField("tags", nil, []json.RawMessage{
+					json.RawMessage(`[{"key":"http.status","value":200},{"key":"error","value":false}]`),
+					json.RawMessage(`[{"key":"db.name","value":"test"}]`)}),
+			).SetMeta(traceMeta)},
+
+		//Custom mapping with durations in nanoseconds
+		{TraceMapping{TraceIDField: "trace", SpanIDField: "_id", DurationField: "nanos", DurationUnit: "ns"},
+			[]primitive.D{
+				{{Key: "trace", Value: "t1"}, {Key: "_id", Value: "s1"}, {Key: "nanos", Value: int64(1500000)}},
+			},
+			data.NewFrame("trace",
+				data.NewField("traceID", nil, []string{"t1"}),
+				data.NewField("spanID", nil, []string{"s1"}),
+				data.NewField("parentSpanID", nil, []*string{nil}),
+				data.NewField("serviceName", nil, []*string{nil}),
+				data.NewField("operationName", nil, []*string{nil}),
+				data.NewField("startTime", nil, []*string{nil}),
+				data.NewField("duration", nil, []float64{1.5}),
+				data.NewField("tags", nil, []json.RawMessage{json.RawMessage(`[]`)}),
+			).SetMeta(traceMeta)},
+	}
+
+	for _, test := range tests {
+
+		tb, _ := NewTraceBuilder(test.mapping, field.Options{})
+		for _, record := range test.records {
+			tb.ProcessRecord(record)
+		}
+
+		if got := tb.Build(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("TraceBuilder.Build(%v) = %v", test.records, got)
+		}
+	}
+
+	if _, err := NewTraceBuilder(TraceMapping{DurationUnit: "days"}, field.Options{}); err == nil || err.Error() != "'days' is not a valid duration unit" {
+		t.Errorf("NewTraceBuilder(days) error = %v", err)
+	}
+}
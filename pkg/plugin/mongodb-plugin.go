@@ -3,15 +3,21 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"github.com/maikuroashi/mongodb-datasource/pkg/format"
 	"github.com/maikuroashi/mongodb-datasource/pkg/query"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // newDatasource returns datasource.ServeOpts.
@@ -62,8 +68,55 @@ func (td *MongoDBDatasource) QueryData(ctx context.Context, req *backend.QueryDa
 }
 
 type queryModel struct {
-	Format    string `json:"format"`
-	QueryText string `json:"queryText"`
+	Format             string                       `json:"format"`
+	QueryText          string                       `json:"queryText"`
+	Decimal128         string                       `json:"decimal128"`
+	ExactDecimalFields []string                     `json:"exactDecimalFields"`
+	SeriesBy           []string                     `json:"seriesBy"`
+	DisplayName        string                       `json:"displayName"`
+	TimeField          string                       `json:"timeField"`
+	BodyField          string                       `json:"bodyField"`
+	SeverityField      string                       `json:"severityField"`
+	TraceIDField       string                       `json:"traceIdField"`
+	SpanIDField        string                       `json:"spanIdField"`
+	ParentSpanIDField  string                       `json:"parentSpanIdField"`
+	ServiceNameField   string                       `json:"serviceNameField"`
+	OperationNameField string                       `json:"operationNameField"`
+	StartTimeField     string                       `json:"startTimeField"`
+	DurationField      string                       `json:"durationField"`
+	DurationUnit       string                       `json:"durationUnit"`
+	TagsField          string                       `json:"tagsField"`
+	IdField            string                       `json:"idField"`
+	TitleField         string                       `json:"titleField"`
+	SubtitleField      string                       `json:"subtitleField"`
+	MainStatField      string                       `json:"mainStatField"`
+	SecondaryStatField string                       `json:"secondaryStatField"`
+	ParentField        string                       `json:"parentField"`
+	EdgesField         string                       `json:"edgesField"`
+	BucketField        string                       `json:"bucketField"`
+	CountField         string                       `json:"countField"`
+	DocumentField      string                       `json:"documentField"`
+	DocumentJSON       string                       `json:"documentJson"`
+	ObjectIdTime       bool                         `json:"objectIdTime"`
+	ObjectIdTimeFields []string                     `json:"objectIdTimeFields"`
+	GeoJSON            bool                         `json:"geoJson"`
+	GeoJSONFields      []string                     `json:"geoJsonFields"`
+	Schema             []field.Column               `json:"schema"`
+	Columns            []field.OutputColumn         `json:"columns"`
+	ExcludeColumns     []string                     `json:"excludeColumns"`
+	OnlyColumns        bool                         `json:"onlyColumns"`
+	ColumnOrder        string                       `json:"columnOrder"`
+	FieldConfig        map[string]field.FieldConfig `json:"fieldConfig"`
+	Resample           bool                         `json:"resample"`
+	ResampleStep       string                       `json:"resampleStep"`
+	FillMode           string                       `json:"fillMode"`
+	Aggregation        string                       `json:"aggregation"`
+	Downsample         string                       `json:"downsample"`
+	PivotKey           string                       `json:"pivotKey"`
+	PivotValue         string                       `json:"pivotValue"`
+	PivotAggregation   string                       `json:"pivotAggregation"`
+	Facets             bool                         `json:"facets"`
+	ChildFrames        []string                     `json:"childFrames"`
 }
 
 // CheckHealth handles health checks sent from Grafana to the plugin.
@@ -97,10 +150,16 @@ func (td *MongoDBDatasource) pluginInstance(pluginContext backend.PluginContext)
 }
 
 type pluginInstance struct {
-	queryService query.QueryService
-	maxResult    int
+	queryService       query.QueryService
+	maxResult          int
+	maxResultBytes     int
+	uuidRepresentation field.UUIDRepresentation
 }
 
+// defaultMaxResultBytes keeps results well within the default gRPC message
+// size limit of Grafana.
+const defaultMaxResultBytes = 16 * 1024 * 1024
+
 func newDataSourceInstance(setting backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
 
 	url := setting.URL
@@ -117,44 +176,499 @@ func newDataSourceInstance(setting backend.DataSourceInstanceSettings) (instance
 		maxResult = int(value.(float64))
 	}
 
+	// a cleared setting is saved as null
+	maxResultBytes := defaultMaxResultBytes
+	if value, ok := customSettings["maxResultBytes"].(float64); ok {
+		maxResultBytes = int(value)
+	}
+
+	uuidRepresentation := field.UUIDPythonLegacy
+	value, ok = customSettings["uuidRepresentation"]
+	if ok {
+		text, _ := value.(string)
+		uuidRepresentation = field.UUIDRepresentation(text)
+		if !uuidRepresentation.IsValid() {
+			return nil, fmt.Errorf("'%s' is not a valid UUID representation", uuidRepresentation)
+		}
+	}
+
 	queryService, err := query.NewQueryService(context.Background(), url, defaultDB, user, password)
 	if err != nil {
 		return nil, err
 	}
 
 	return &pluginInstance{
-		queryService: queryService,
-		maxResult:    maxResult,
+		queryService:       queryService,
+		maxResult:          maxResult,
+		maxResultBytes:     maxResultBytes,
+		uuidRepresentation: uuidRepresentation,
 	}, err
 }
 
-func (is *pluginInstance) query(ctx context.Context, query backend.DataQuery) backend.DataResponse {
+func (is *pluginInstance) query(ctx context.Context, q backend.DataQuery) backend.DataResponse {
 
 	response := backend.DataResponse{}
 
 	// Unmarshal the json into our queryModel
 	var qm queryModel
-	response.Error = json.Unmarshal(query.JSON, &qm)
+	response.Error = json.Unmarshal(q.JSON, &qm)
 	if response.Error != nil {
 		return response
 	}
+	qm.QueryText = query.ExpandMacros(qm.QueryText, q.TimeRange.From, q.TimeRange.To)
 
 	// Log a warning if `Format` is empty.
-	if qm.Format == "" {
-		log.DefaultLogger.Warn("format is empty. defaulting to time series")
+	resultFormat := format.Format(qm.Format)
+	if resultFormat == "" {
+		log.DefaultLogger.Warn("format is empty. defaulting to table")
+		resultFormat = format.Table
 	}
 
-	ds := field.NewFieldBuilder(10)
-	response.Error = is.queryService.RunQuery(ctx, qm.QueryText, is.maxResult, ds.ProcessRecord)
-	if response.Error != nil {
+	if !resultFormat.IsValid() {
+		response.Error = fmt.Errorf("'%s' is not a valid format", qm.Format)
+		return response
+	}
+
+	decimal128 := field.Decimal128Mode(qm.Decimal128)
+	if !decimal128.IsValid() {
+		response.Error = fmt.Errorf("'%s' is not a valid decimal128 mode", qm.Decimal128)
+		return response
+	}
+
+	documentJSON := field.JSONMode(qm.DocumentJSON)
+	if !documentJSON.IsValid() {
+		response.Error = fmt.Errorf("'%s' is not a valid document JSON mode", qm.DocumentJSON)
+		return response
+	}
+
+	columnOrder := field.ColumnOrder(qm.ColumnOrder)
+	if !columnOrder.IsValid() {
+		response.Error = fmt.Errorf("'%s' is not a valid column order", qm.ColumnOrder)
+		return response
+	}
+
+	if !format.DownsampleMode(qm.Downsample).IsValid() {
+		response.Error = fmt.Errorf("'%s' is not a valid downsample mode", qm.Downsample)
 		return response
 	}
 
+	if !format.Aggregation(qm.PivotAggregation).IsValid() {
+		response.Error = fmt.Errorf("'%s' is not a valid aggregation", qm.PivotAggregation)
+		return response
+	}
+
+	for _, column := range qm.Schema {
+		if !column.Type.IsValid() {
+			response.Error = fmt.Errorf("'%s' is not a valid column type", column.Type)
+			return response
+		}
+		if !column.Unit.IsValid() {
+			response.Error = fmt.Errorf("'%s' is not a valid epoch unit", column.Unit)
+			return response
+		}
+	}
+
+	// Default to the creation time of the document id.
+	objectIdTimeFields := qm.ObjectIdTimeFields
+	if qm.ObjectIdTime && len(objectIdTimeFields) == 0 {
+		objectIdTimeFields = []string{"_id"}
+	}
+
+	options := field.Options{
+		Decimal128:         decimal128,
+		ExactDecimalFields: qm.ExactDecimalFields,
+		ObjectIdTimeFields: objectIdTimeFields,
+		UUIDRepresentation: is.uuidRepresentation,
+		DetectGeoJSON:      qm.GeoJSON,
+		GeoJSONFields:      qm.GeoJSONFields,
+		DocumentField:      qm.DocumentField,
+		DocumentJSON:       documentJSON,
+		Schema:             qm.Schema,
+		DerivedSchema:      query.ResultSchema(qm.QueryText),
+		Columns:            qm.Columns,
+		ExcludeColumns:     qm.ExcludeColumns,
+		OnlyColumns:        qm.OnlyColumns,
+		ColumnOrder:        columnOrder,
+		FieldConfig:        qm.FieldConfig,
+	}
+
+	switch resultFormat {
+	case format.Logs:
+		response.Frames, response.Error = is.queryLogs(ctx, qm, options)
+	case format.Trace:
+		response.Frames, response.Error = is.queryTrace(ctx, qm, options)
+	case format.NodeGraph:
+		response.Frames, response.Error = is.queryNodeGraph(ctx, qm, options)
+	case format.Histogram:
+		response.Frames, response.Error = is.queryHistogram(ctx, qm, options)
+	case format.Heatmap:
+		response.Frames, response.Error = is.queryHeatmap(ctx, qm, options)
+	default:
+		if qm.Facets || query.IsFacetQuery(qm.QueryText) {
+			response.Frames, response.Error = is.queryFacets(ctx, q, qm, resultFormat, options)
+		} else {
+			response.Frames, response.Error = is.queryTable(ctx, q, qm, resultFormat, options)
+		}
+	}
+
+	return response
+}
+
+func (is *pluginInstance) queryTable(ctx context.Context, q backend.DataQuery, qm queryModel, resultFormat format.Format, options field.Options) ([]*data.Frame, error) {
+
+	resample, err := resampleOptions(q, qm)
+	if err != nil {
+		return nil, err
+	}
+
+	// the child frame fields are returned as frames of their own, so are
+	// not read into the parent or counted twice against the size limit
+	var children *format.ChildFramesBuilder
+	if len(qm.ChildFrames) > 0 {
+		children = format.NewChildFramesBuilder(qm.ChildFrames, options)
+		options.IgnoredFields = qm.ChildFrames
+		options.ExcludeColumns = append(append([]string{}, options.ExcludeColumns...), qm.ChildFrames...)
+	}
+
+	ds := field.NewFieldBuilderWithOptions(10, options)
+	resultSize := func() int {
+		if children == nil {
+			return ds.Size()
+		}
+		return ds.Size() + children.Size()
+	}
+
+	truncated := false
+	err = is.queryService.RunRawQuery(ctx, qm.QueryText, is.maxResult, func(raw bson.Raw) error {
+
+		err := ds.ProcessRawRecord(raw)
+		if err == nil && children != nil {
+			err = children.ProcessRawRecord(raw)
+		}
+		if err == nil && is.maxResultBytes > 0 && resultSize() > is.maxResultBytes {
+			truncated = true
+			err = query.ErrStopQuery
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// create data frame response
 	frame := data.NewFrame("response", ds.BuildFields()...)
-	response.Frames = append(response.Frames, frame)
+	if notices := ds.Notices(); len(notices) > 0 {
+		frame.AppendNotices(notices...)
+	}
+	is.addResultMeta(frame, resultSize(), truncated)
 
-	return response
+	frames, err := seriesFrames(frame, qm)
+	if err != nil {
+		return nil, err
+	}
+
+	frames, err = formatFrames(frames, q, qm, resultFormat, resample)
+	if err != nil || children == nil {
+		return frames, err
+	}
+	return append(frames, children.Build()...), nil
+}
+
+// queryFacets returns one frame for each facet of the single document
+// returned by a $facet stage.
+func (is *pluginInstance) queryFacets(ctx context.Context, q backend.DataQuery, qm queryModel, resultFormat format.Format, options field.Options) ([]*data.Frame, error) {
+
+	// the child frame fields are read from the documents of the query,
+	// not the documents of the facets
+	if len(qm.ChildFrames) > 0 {
+		return nil, errors.New("child frames are not supported for $facet results")
+	}
+
+	resample, err := resampleOptions(q, qm)
+	if err != nil {
+		return nil, err
+	}
+
+	fb := format.NewFacetBuilder(options, is.maxResultBytes)
+	truncated := false
+	err = is.queryService.RunQuery(ctx, qm.QueryText, is.maxResult, func(record primitive.D) {
+		fb.ProcessRecord(record)
+		truncated = fb.Truncated()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	facets := fb.Build()
+	if len(facets) > 0 {
+		is.addResultMeta(facets[0], fb.Size(), truncated)
+	}
+
+	// facets without the fields the query needs are returned as tables
+	requireTime := resultFormat == format.TimeSeries || resample != nil
+	names := append([]string{}, qm.SeriesBy...)
+	if qm.PivotKey != "" || qm.PivotValue != "" {
+		names = append(names, qm.PivotKey, qm.PivotValue)
+	}
+
+	var frames []*data.Frame
+	for _, facet := range facets {
+
+		if !format.HasFields(facet, qm.TimeField, requireTime, names...) {
+			frames = append(frames, facet)
+			continue
+		}
+
+		series, err := seriesFrames(facet, qm)
+		if err == nil {
+			series, err = formatFrames(series, q, qm, resultFormat, resample)
+		}
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, series...)
+	}
+	return frames, nil
+}
+
+// seriesFrames pivots a frame and splits it into one frame per series when
+// requested.
+func seriesFrames(frame *data.Frame, qm queryModel) ([]*data.Frame, error) {
+
+	if qm.PivotKey != "" || qm.PivotValue != "" {
+		var err error
+		frame, err = format.Pivot(frame, format.PivotOptions{
+			KeyField:    qm.PivotKey,
+			ValueField:  qm.PivotValue,
+			Aggregation: format.Aggregation(qm.PivotAggregation),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return format.SplitBySeries(frame, qm.SeriesBy, qm.DisplayName)
+}
+
+// formatFrames converts frames to the result format, resampling and
+// downsampling them when requested.
+func formatFrames(frames []*data.Frame, q backend.DataQuery, qm queryModel, resultFormat format.Format, resample *format.ResampleOptions) ([]*data.Frame, error) {
+
+	var err error
+	for i := range frames {
+		switch {
+		case resultFormat == format.TimeSeries:
+			frames[i], err = format.ToTimeSeries(frames[i], qm.TimeField)
+		case qm.TimeField != "" || resample != nil || qm.Downsample != "":
+			frames[i], err = format.SortByTime(frames[i], qm.TimeField)
+		}
+		if err == nil && resample != nil {
+			frames[i], err = format.Resample(frames[i], *resample)
+		}
+		if err != nil {
+			return nil, err
+		}
+		frames[i] = format.Downsample(frames[i], format.DownsampleMode(qm.Downsample), int(q.MaxDataPoints))
+	}
+	return frames, nil
+}
+
+// resampleOptions returns the options of the resample step of a query, nil
+// when the query is not resampled. The step defaults to the interval of the
+// query.
+func resampleOptions(q backend.DataQuery, qm queryModel) (*format.ResampleOptions, error) {
+
+	if !qm.Resample {
+		return nil, nil
+	}
+
+	step := q.Interval
+	if qm.ResampleStep != "" {
+		var err error
+		step, err = gtime.ParseDuration(qm.ResampleStep)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid resample step", qm.ResampleStep)
+		}
+	}
+
+	fill := format.FillMode(qm.FillMode)
+	if !fill.IsValid() {
+		return nil, fmt.Errorf("'%s' is not a valid fill mode", qm.FillMode)
+	}
+
+	aggregation := format.Aggregation(qm.Aggregation)
+	if !aggregation.IsValid() {
+		return nil, fmt.Errorf("'%s' is not a valid aggregation", qm.Aggregation)
+	}
+
+	return &format.ResampleOptions{
+		Step:        step,
+		From:        q.TimeRange.From,
+		To:          q.TimeRange.To,
+		Fill:        fill,
+		Aggregation: aggregation,
+	}, nil
+}
+
+// runQuery passes the documents of a query to the handler until the size of
+// the documents read exceeds the result size limit. It returns the size of
+// the documents and whether the result was truncated.
+func (is *pluginInstance) runQuery(ctx context.Context, queryText string, handler query.DataHandler) (int, bool, error) {
+
+	size := 0
+	truncated := false
+	err := is.queryService.RunRawQuery(ctx, queryText, is.maxResult, func(raw bson.Raw) error {
+
+		var record primitive.D
+		err := bson.Unmarshal(raw, &record)
+		if err != nil {
+			return err
+		}
+
+		handler(record)
+		size += len(raw)
+		if is.maxResultBytes > 0 && size > is.maxResultBytes {
+			truncated = true
+			return query.ErrStopQuery
+		}
+		return nil
+	})
+	return size, truncated, err
+}
+
+// addResultMeta adds the size stats of a result to a frame, with a warning
+// when the result was truncated to keep within the size limit.
+func (is *pluginInstance) addResultMeta(frame *data.Frame, size int, truncated bool) {
+
+	// frames such as the nodes and edges of a node graph share their meta
+	meta := data.FrameMeta{}
+	if frame.Meta != nil {
+		meta = *frame.Meta
+	}
+	meta.Stats = append(append([]data.QueryStat{}, meta.Stats...), is.sizeStats(size)...)
+
+	if truncated {
+		meta.Notices = append(append([]data.Notice{}, meta.Notices...), data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("the result was truncated as it exceeded the limit of %d bytes", is.maxResultBytes),
+		})
+	}
+	frame.Meta = &meta
+}
+
+// sizeStats reports the size of a result and the limit it is checked
+// against.
+func (is *pluginInstance) sizeStats(size int) []data.QueryStat {
+
+	stats := []data.QueryStat{
+		{FieldConfig: data.FieldConfig{DisplayName: "Result size", Unit: "bytes"}, Value: float64(size)},
+	}
+	if is.maxResultBytes > 0 {
+		stats = append(stats, data.QueryStat{
+			FieldConfig: data.FieldConfig{DisplayName: "Result size limit", Unit: "bytes"},
+			Value:       float64(is.maxResultBytes),
+		})
+	}
+	return stats
+}
+
+func (is *pluginInstance) queryLogs(ctx context.Context, qm queryModel, options field.Options) ([]*data.Frame, error) {
+
+	lb := format.NewLogsBuilder(format.LogsMapping{
+		TimeField:     qm.TimeField,
+		BodyField:     qm.BodyField,
+		SeverityField: qm.SeverityField,
+	}, options)
+	size, truncated, err := is.runQuery(ctx, qm.QueryText, lb.ProcessRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	frame, err := lb.Build()
+	if err != nil {
+		return nil, err
+	}
+	is.addResultMeta(frame, size, truncated)
+	return []*data.Frame{frame}, nil
+}
+
+func (is *pluginInstance) queryTrace(ctx context.Context, qm queryModel, options field.Options) ([]*data.Frame, error) {
+
+	tb, err := format.NewTraceBuilder(format.TraceMapping{
+		TraceIDField:       qm.TraceIDField,
+		SpanIDField:        qm.SpanIDField,
+		ParentSpanIDField:  qm.ParentSpanIDField,
+		ServiceNameField:   qm.ServiceNameField,
+		OperationNameField: qm.OperationNameField,
+		StartTimeField:     qm.StartTimeField,
+		DurationField:      qm.DurationField,
+		DurationUnit:       qm.DurationUnit,
+		TagsField:          qm.TagsField,
+	}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	size, truncated, err := is.runQuery(ctx, qm.QueryText, tb.ProcessRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := tb.Build()
+	is.addResultMeta(frame, size, truncated)
+	return []*data.Frame{frame}, nil
+}
+
+func (is *pluginInstance) queryNodeGraph(ctx context.Context, qm queryModel, options field.Options) ([]*data.Frame, error) {
+
+	nb := format.NewNodeGraphBuilder(format.NodeGraphMapping{
+		IdField:            qm.IdField,
+		TitleField:         qm.TitleField,
+		SubtitleField:      qm.SubtitleField,
+		MainStatField:      qm.MainStatField,
+		SecondaryStatField: qm.SecondaryStatField,
+		ParentField:        qm.ParentField,
+		EdgesField:         qm.EdgesField,
+	}, options)
+
+	size, truncated, err := is.runQuery(ctx, qm.QueryText, nb.ProcessRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := nb.Build()
+	is.addResultMeta(frames[0], size, truncated)
+	return frames, nil
+}
+
+func (is *pluginInstance) queryHistogram(ctx context.Context, qm queryModel, options field.Options) ([]*data.Frame, error) {
+
+	hb := format.NewHistogramBuilder(query.BucketBoundaries(qm.QueryText), options)
+	size, truncated, err := is.runQuery(ctx, qm.QueryText, hb.ProcessRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := hb.Build()
+	is.addResultMeta(frame, size, truncated)
+	return []*data.Frame{frame}, nil
+}
+
+func (is *pluginInstance) queryHeatmap(ctx context.Context, qm queryModel, options field.Options) ([]*data.Frame, error) {
+
+	hb := format.NewHeatmapBuilder(format.HeatmapMapping{
+		TimeField:   qm.TimeField,
+		BucketField: qm.BucketField,
+		CountField:  qm.CountField,
+	}, options)
+
+	size, truncated, err := is.runQuery(ctx, qm.QueryText, hb.ProcessRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := hb.Build()
+	is.addResultMeta(frame, size, truncated)
+	return []*data.Frame{frame}, nil
 }
 
 func (is *pluginInstance) Dispose() {
@@ -0,0 +1,49 @@
+package query
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var oidFilterRegex = regexp.MustCompile(`\$__oidFilter\(\s*([^)]*?)\s*\)`)
+
+// ExpandMacros replaces the time range macros in a query:
+//
+//	$__oidFrom          the smallest ObjectId created at the start of the range
+//	$__oidTo            the largest ObjectId created at the end of the range
+//	$__oidFilter(field) "field": {"$gte": $__oidFrom, "$lte": $__oidTo}
+func ExpandMacros(queryString string, from time.Time, to time.Time) string {
+
+	oidFrom := oidLiteral(objectIdFromTime(from, 0x00))
+	oidTo := oidLiteral(objectIdFromTime(to, 0xff))
+
+	result := oidFilterRegex.ReplaceAllStringFunc(queryString, func(macro string) string {
+		field := strings.Trim(oidFilterRegex.FindStringSubmatch(macro)[1], `"'`)
+		return fmt.Sprintf(`%q: {"$gte": %s, "$lte": %s}`, field, oidFrom, oidTo)
+	})
+
+	result = strings.ReplaceAll(result, "$__oidFrom", oidFrom)
+	result = strings.ReplaceAll(result, "$__oidTo", oidTo)
+	return result
+}
+
+// objectIdFromTime returns an ObjectId with the timestamp bytes set to the
+// given time and the remaining bytes set to fill.
+func objectIdFromTime(t time.Time, fill byte) primitive.ObjectID {
+
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[0:4], uint32(t.Unix()))
+	for i := 4; i < len(id); i++ {
+		id[i] = fill
+	}
+	return id
+}
+
+func oidLiteral(id primitive.ObjectID) string {
+	return fmt.Sprintf(`{"$oid": %q}`, id.Hex())
+}
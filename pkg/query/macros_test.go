@@ -0,0 +1,60 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExpandMacros(t *testing.T) {
+
+	from := time.Unix(1620586358, 0)
+	to := time.Unix(1620590000, 999)
+	oidFrom := `{"$oid": "60982f760000000000000000"}`
+	oidTo := `{"$oid": "60983db0ffffffffffffffff"}`
+
+	var tests = []struct {
+		queryString string
+		want        string
+	}{
+		//No macros
+		{`db.test.find({"a": 1})`,
+			`db.test.find({"a": 1})`},
+
+		//From and to
+		{`db.test.find({"_id": {"$gt": $__oidFrom, "$lt": $__oidTo}})`,
+			`db.test.find({"_id": {"$gt": ` + oidFrom + `, "$lt": ` + oidTo + `}})`},
+
+		//Filter
+		{`db.test.find({$__oidFilter(_id), "a": 1})`,
+			`db.test.find({"_id": {"$gte": ` + oidFrom + `, "$lte": ` + oidTo + `}, "a": 1})`},
+
+		//Filter with a quoted field
+		{`db.test.aggregate([{"$match": {$__oidFilter( "ref" )}}])`,
+			`db.test.aggregate([{"$match": {"ref": {"$gte": ` + oidFrom + `, "$lte": ` + oidTo + `}}}])`},
+	}
+
+	for _, test := range tests {
+		if got := ExpandMacros(test.queryString, from, to); got != test.want {
+			t.Errorf("ExpandMacros(%q) = %q", test.queryString, got)
+		}
+	}
+}
+
+func TestExpandMacrosParseQuery(t *testing.T) {
+
+	from := time.Unix(1620586358, 0)
+	to := time.Unix(1620590000, 0)
+	queryString := ExpandMacros(`db.test.find({$__oidFilter(_id)})`, from, to)
+
+	wantFrom, _ := primitive.ObjectIDFromHex("60982f760000000000000000")
+	wantTo, _ := primitive.ObjectIDFromHex("60983db0ffffffffffffffff")
+	want := primitive.D{{Key: "_id", Value: primitive.D{{Key: "$gte", Value: wantFrom}, {Key: "$lte", Value: wantTo}}}}
+
+	got, err := parseQuery(queryString, "db1")
+	if err != nil || !reflect.DeepEqual(got.Query, want) {
+		t.Errorf("parseQuery(%q) = (%v,%v)", queryString, got, err)
+	}
+}
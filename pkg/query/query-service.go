@@ -14,6 +14,10 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrStopQuery can be returned by a handler to stop reading the results of a
+// query without failing it.
+var ErrStopQuery = errors.New("stop query")
+
 var dateLiteralRegex = regexp.MustCompile(`^\${new Date\((\d+)\)}`)
 var escapeDateLiteralRegex = regexp.MustCompile(`([^"]+)(new Date\(\d+\))([^"]+)`)
 
@@ -21,6 +25,7 @@ type QueryService interface {
 	Disconnect(ctx context.Context) error
 	Ping(ctx context.Context) error
 	RunQuery(ctx context.Context, queryString string, limit int, handler DataHandler) error
+	RunRawQuery(ctx context.Context, queryString string, limit int, handler RawDataHandler) error
 }
 
 type queryService struct {
@@ -39,6 +44,10 @@ type mongoQuery struct {
 
 type DataHandler = func(primitive.D)
 
+// RawDataHandler receives the undecoded BSON of each document. The document
+// is only valid until the handler returns.
+type RawDataHandler = func(bson.Raw) error
+
 func NewQueryService(ctx context.Context, url string, defaultDB string, user string, password string) (QueryService, error) {
 
 	clientOptions := options.Client()
@@ -62,6 +71,20 @@ func (qs *queryService) Ping(ctx context.Context) error {
 
 func (qs *queryService) RunQuery(ctx context.Context, queryString string, limit int, handler DataHandler) error {
 
+	return qs.RunRawQuery(ctx, queryString, limit, func(raw bson.Raw) error {
+
+		var rec primitive.D
+		err := bson.Unmarshal(raw, &rec)
+		if err != nil {
+			return err
+		}
+		handler(rec)
+		return nil
+	})
+}
+
+func (qs *queryService) RunRawQuery(ctx context.Context, queryString string, limit int, handler RawDataHandler) error {
+
 	mongoQuery, err := parseQuery(queryString, qs.defaultDB)
 	if err != nil {
 		return err
@@ -89,12 +112,13 @@ func (qs *queryService) RunQuery(ctx context.Context, queryString string, limit
 			break
 		}
 
-		var rec primitive.D
-		err := cur.Decode(&rec)
+		err := handler(cur.Current)
+		if err == ErrStopQuery {
+			break
+		}
 		if err != nil {
 			return err
 		}
-		handler(rec)
 	}
 
 	err = cur.Err()
@@ -0,0 +1,285 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// operatorTypes are the types of the results of expression and accumulator
+// operators, other operators are assumed to return strings.
+var operatorTypes = map[string]field.ColumnType{
+	"$avg":            field.ColumnFloat,
+	"$stdDevPop":      field.ColumnFloat,
+	"$stdDevSamp":     field.ColumnFloat,
+	"$add":            field.ColumnFloat,
+	"$subtract":       field.ColumnFloat,
+	"$multiply":       field.ColumnFloat,
+	"$divide":         field.ColumnFloat,
+	"$mod":            field.ColumnFloat,
+	"$abs":            field.ColumnFloat,
+	"$round":          field.ColumnFloat,
+	"$toDouble":       field.ColumnFloat,
+	"$count":          field.ColumnInt,
+	"$size":           field.ColumnInt,
+	"$toInt":          field.ColumnInt,
+	"$toLong":         field.ColumnInt,
+	"$strLenCP":       field.ColumnInt,
+	"$toDate":         field.ColumnTime,
+	"$dateFromString": field.ColumnTime,
+	"$dateFromParts":  field.ColumnTime,
+	"$dateTrunc":      field.ColumnTime,
+	"$toBool":         field.ColumnBool,
+	"$and":            field.ColumnBool,
+	"$or":             field.ColumnBool,
+	"$not":            field.ColumnBool,
+	"$eq":             field.ColumnBool,
+	"$ne":             field.ColumnBool,
+	"$gt":             field.ColumnBool,
+	"$gte":            field.ColumnBool,
+	"$lt":             field.ColumnBool,
+	"$lte":            field.ColumnBool,
+	"$in":             field.ColumnBool,
+}
+
+// inputTypedOperators are the accumulators whose results have the type of
+// their input, which is not known from the query.
+var inputTypedOperators = map[string]bool{
+	"$first":        true,
+	"$last":         true,
+	"$min":          true,
+	"$max":          true,
+	"$top":          true,
+	"$bottom":       true,
+	"$push":         true,
+	"$addToSet":     true,
+	"$mergeObjects": true,
+}
+
+// ResultSchema returns the fields of the documents returned by a query when
+// they can be derived from the projection of a find or from the last $project,
+// $group or $count stage of an aggregate. It returns nil when the fields are
+// not known.
+func ResultSchema(queryString string) []field.Column {
+
+	mongoQuery, err := parseQuery(queryString, "")
+	if err != nil {
+		return nil
+	}
+
+	switch mongoQuery.Method {
+	case "find":
+		projection, _ := mongoQuery.Projection.(primitive.D)
+		return projectionSchema(projection)
+	case "aggregate":
+		pipeline, _ := mongoQuery.Query.(primitive.A)
+		return pipelineSchema(pipeline)
+	default:
+		return nil
+	}
+}
+
+// IsFacetQuery reports whether a query is an aggregate whose last stage is a
+// $facet, which returns a single document holding the results of each facet.
+func IsFacetQuery(queryString string) bool {
+
+	mongoQuery, err := parseQuery(queryString, "")
+	if err != nil || mongoQuery.Method != "aggregate" {
+		return false
+	}
+
+	pipeline, _ := mongoQuery.Query.(primitive.A)
+	if len(pipeline) == 0 {
+		return false
+	}
+
+	stage, ok := pipeline[len(pipeline)-1].(primitive.D)
+	return ok && len(stage) == 1 && stage[0].Key == "$facet"
+}
+
+// BucketBoundaries returns the boundaries of the last $bucket stage of an
+// aggregate, nil when there is none.
+func BucketBoundaries(queryString string) primitive.A {
+
+	mongoQuery, err := parseQuery(queryString, "")
+	if err != nil || mongoQuery.Method != "aggregate" {
+		return nil
+	}
+
+	pipeline, _ := mongoQuery.Query.(primitive.A)
+	for i := len(pipeline) - 1; i >= 0; i-- {
+
+		stage, ok := pipeline[i].(primitive.D)
+		if !ok || len(stage) != 1 || stage[0].Key != "$bucket" {
+			continue
+		}
+
+		bucket, _ := stage[0].Value.(primitive.D)
+		boundaries, _ := bucket.Map()["boundaries"].(primitive.A)
+		return boundaries
+	}
+	return nil
+}
+
+func pipelineSchema(pipeline primitive.A) []field.Column {
+
+	for i := len(pipeline) - 1; i >= 0; i-- {
+
+		stage, ok := pipeline[i].(primitive.D)
+		if !ok || len(stage) != 1 {
+			return nil
+		}
+
+		switch stage[0].Key {
+		case "$match", "$sort", "$limit", "$skip":
+			// the stages do not change the fields of the documents
+		case "$project":
+			projection, _ := stage[0].Value.(primitive.D)
+			return projectionSchema(projection)
+		case "$group":
+			group, _ := stage[0].Value.(primitive.D)
+			return groupSchema(group)
+		case "$count":
+			name, _ := stage[0].Value.(string)
+			return []field.Column{{Name: name, Type: field.ColumnInt}}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// projectionSchema returns the fields of an inclusion projection, exclusion
+// projections do not name the fields that remain. Only the names of included
+// fields are known, so they are returned as strings.
+func projectionSchema(projection primitive.D) []field.Column {
+
+	if len(projection) == 0 {
+		return nil
+	}
+
+	id := field.Column{Name: "_id", Type: field.ColumnString}
+	includeId := true
+	var columns []field.Column
+	for _, e := range projection {
+
+		column := field.Column{Name: e.Key, Type: field.ColumnString}
+		if include, ok := projectionFlag(e.Value); ok {
+			if e.Key == "_id" {
+				includeId = include
+				continue
+			}
+			if !include {
+				return nil
+			}
+		} else {
+			column.Type, ok = expressionType(e.Value)
+			if !ok {
+				return nil
+			}
+		}
+
+		if e.Key == "_id" {
+			id = column
+			continue
+		}
+
+		// nested fields are returned in their parent document
+		if i := strings.Index(column.Name, "."); i != -1 {
+			column = field.Column{Name: column.Name[:i], Type: field.ColumnString}
+		}
+		if column.Name == "_id" {
+			id = column
+		} else if !containsColumn(columns, column.Name) {
+			columns = append(columns, column)
+		}
+	}
+
+	if includeId {
+		columns = append([]field.Column{id}, columns...)
+	}
+	return columns
+}
+
+func groupSchema(group primitive.D) []field.Column {
+
+	var columns []field.Column
+	for _, e := range group {
+		columnType, ok := expressionType(e.Value)
+		if !ok {
+			return nil
+		}
+		columns = append(columns, field.Column{Name: e.Key, Type: columnType})
+	}
+	return columns
+}
+
+// projectionFlag reports whether a projection value includes or excludes a
+// field, the second result is false for expressions.
+func projectionFlag(value interface{}) (bool, bool) {
+
+	switch value := value.(type) {
+	case bool:
+		return value, true
+	case int32:
+		return value != 0, true
+	case int64:
+		return value != 0, true
+	case float64:
+		return value != 0, true
+	default:
+		return false, false
+	}
+}
+
+// expressionType returns the type of the result of an expression, the second
+// result is false when the type depends on the documents.
+func expressionType(value interface{}) (field.ColumnType, bool) {
+
+	switch value := value.(type) {
+	case int32, int64:
+		return field.ColumnInt, true
+	case float64:
+		return field.ColumnFloat, true
+	case bool:
+		return field.ColumnBool, true
+	case primitive.DateTime:
+		return field.ColumnTime, true
+	case string:
+		// field paths and variables have the type of the documents' values
+		if strings.HasPrefix(value, "$") {
+			return "", false
+		}
+	case primitive.D:
+		if len(value) == 1 {
+			operator := value[0].Key
+			if columnType, ok := operatorTypes[operator]; ok {
+				return columnType, true
+			}
+			if inputTypedOperators[operator] {
+				return "", false
+			}
+			// the sum of a constant, such as {$sum: 1}, has its type
+			if operator == "$sum" {
+				switch value[0].Value.(type) {
+				case int32, int64, float64:
+					return expressionType(value[0].Value)
+				default:
+					return "", false
+				}
+			}
+		}
+	}
+	return field.ColumnString, true
+}
+
+func containsColumn(columns []field.Column, name string) bool {
+
+	for _, column := range columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maikuroashi/mongodb-datasource/pkg/field"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResultSchema(t *testing.T) {
+
+	var tests = []struct {
+		queryString string
+		want        []field.Column
+	}{
+		//Invalid query
+		{"wibble", nil},
+
+		//Find without projection
+		{`db.test.find({"a": 1})`, nil},
+
+		//Find inclusion projection
+		{`db.test.find({}, {"a": 1, "b.c": true, "b.d": 1})`,
+			[]field.Column{
+				{Name: "_id", Type: field.ColumnString},
+				{Name: "a", Type: field.ColumnString},
+				{Name: "b", Type: field.ColumnString},
+			}},
+
+		//Find projection without id
+		{`db.test.find({}, {"a": 1, "_id": 0})`,
+			[]field.Column{
+				{Name: "a", Type: field.ColumnString},
+			}},
+
+		//Find exclusion projection
+		{`db.test.find({}, {"a": 0})`, nil},
+
+		//Aggregate project stage with expressions
+		{`db.test.aggregate([{"$project": {"_id": 0, "time": {"$toDate": "$ts"}, "total": {"$add": ["$a", "$b"]}, "name": 1}}, {"$sort": {"time": 1}}])`,
+			[]field.Column{
+				{Name: "time", Type: field.ColumnTime},
+				{Name: "total", Type: field.ColumnFloat},
+				{Name: "name", Type: field.ColumnString},
+			}},
+
+		//Aggregate group stage
+		{`db.test.aggregate([{"$match": {}}, {"$group": {"_id": null, "count": {"$count": {}}, "avg": {"$avg": "$value"}}}, {"$limit": 10}])`,
+			[]field.Column{
+				{Name: "_id", Type: field.ColumnString},
+				{Name: "count", Type: field.ColumnInt},
+				{Name: "avg", Type: field.ColumnFloat},
+			}},
+
+		//Aggregate group stage counting with a constant sum
+		{`db.test.aggregate([{"$group": {"_id": "total", "n": {"$sum": 1}, "w": {"$sum": 0.5}}}])`,
+			[]field.Column{
+				{Name: "_id", Type: field.ColumnString},
+				{Name: "n", Type: field.ColumnInt},
+				{Name: "w", Type: field.ColumnFloat},
+			}},
+
+		//Aggregate group stage with accumulators typed by their input
+		{`db.test.aggregate([{"$group": {"_id": "$host", "time": {"$first": "$time"}}}])`, nil},
+		{`db.test.aggregate([{"$group": {"_id": "$host", "total": {"$sum": "$value"}}}])`, nil},
+		{`db.test.aggregate([{"$project": {"peak": {"$max": "$values"}}}])`, nil},
+
+		//Aggregate stages with field paths typed by the documents
+		{`db.test.aggregate([{"$group": {"_id": "$host", "n": {"$sum": 1}}}])`, nil},
+		{`db.test.aggregate([{"$project": {"time": "$ts", "now": "$$NOW"}}])`, nil},
+
+		//Aggregate count stage
+		{`db.test.aggregate([{"$count": "total"}])`,
+			[]field.Column{
+				{Name: "total", Type: field.ColumnInt},
+			}},
+
+		//Aggregate ending in a stage with unknown fields
+		{`db.test.aggregate([{"$project": {"a": 1}}, {"$unwind": "$a"}])`, nil},
+
+		//Empty aggregate
+		{`db.test.aggregate()`, nil},
+	}
+
+	for _, test := range tests {
+		if got := ResultSchema(test.queryString); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ResultSchema(%q) = %v", test.queryString, got)
+		}
+	}
+}
+
+func TestIsFacetQuery(t *testing.T) {
+
+	var tests = []struct {
+		queryString string
+		want        bool
+	}{
+		//Aggregate ending in a facet stage
+		{`db.test.aggregate([{"$match": {}}, {"$facet": {"a": [{"$count": "n"}], "b": [{"$limit": 5}]}}])`, true},
+
+		//Aggregate with a facet stage before the last stage
+		{`db.test.aggregate([{"$facet": {"a": [{"$count": "n"}]}}, {"$project": {"a": 1}}])`, false},
+
+		//Find
+		{`db.test.find({"$facet": 1})`, false},
+
+		//Invalid query
+		{"wibble", false},
+	}
+
+	for _, test := range tests {
+		if got := IsFacetQuery(test.queryString); got != test.want {
+			t.Errorf("IsFacetQuery(%q) = %v", test.queryString, got)
+		}
+	}
+}
+
+func TestBucketBoundaries(t *testing.T) {
+
+	var tests = []struct {
+		queryString string
+		want        primitive.A
+	}{
+		//Bucket stage
+		{`db.test.aggregate([{"$bucket": {"groupBy": "$price", "boundaries": [0, 10, 20.5], "default": "Other"}}, {"$sort": {"_id": 1}}])`,
+			primitive.A{int32(0), int32(10), 20.5}},
+
+		//Bucket auto stage
+		{`db.test.aggregate([{"$bucketAuto": {"groupBy": "$price", "buckets": 5}}])`, nil},
+
+		//Find
+		{`db.test.find({"$bucket": 1})`, nil},
+
+		//Invalid query
+		{"wibble", nil},
+	}
+
+	for _, test := range tests {
+		if got := BucketBoundaries(test.queryString); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("BucketBoundaries(%q) = %v", test.queryString, got)
+		}
+	}
+}
@@ -18,6 +18,23 @@ export class ConfigEditor extends PureComponent<Props, State> {
     };
     onOptionsChange({ ...options, jsonData });
   };
+  onMaxResultBytesChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onOptionsChange, options } = this.props;
+    const jsonData = {
+      ...options.jsonData,
+      // an empty box restores the default, 0 means no limit
+      maxResultBytes: event.target.value === '' ? undefined : parseInt(event.target.value, 10),
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+  onUuidRepresentationChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onOptionsChange, options } = this.props;
+    const jsonData = {
+      ...options.jsonData,
+      uuidRepresentation: event.target.value as MongoDBDataSourceOptions['uuidRepresentation'],
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
   onUrlChange = (event: ChangeEvent<HTMLInputElement>) => {
     const { onOptionsChange, options } = this.props;
     onOptionsChange({ ...options, url: event.target.value });
@@ -123,6 +140,30 @@ export class ConfigEditor extends PureComponent<Props, State> {
               placeholder="1000"
             />
           </div>
+          <div className="gf-form">
+            <FormField
+              label="Max Bytes"
+              labelWidth={6}
+              inputWidth={20}
+              onChange={this.onMaxResultBytesChange}
+              value={jsonData.maxResultBytes ?? ''}
+              placeholder="16777216"
+            />
+          </div>
+        </div>
+        <h3 className="page-heading">Data Types</h3>
+        <div className="gf-form-group">
+          <div className="gf-form">
+            <FormField
+              label="Legacy UUID"
+              labelWidth={6}
+              inputWidth={20}
+              onChange={this.onUuidRepresentationChange}
+              value={jsonData.uuidRepresentation || ''}
+              placeholder="pythonLegacy"
+              tooltip="Byte order of subtype 3 UUIDs: pythonLegacy, javaLegacy or csharpLegacy"
+            />
+          </div>
         </div>
       </div>
     );
@@ -1,7 +1,94 @@
 import { DataQuery, DataSourceJsonData } from '@grafana/data';
 
+export type Decimal128Mode = 'string' | 'float';
+
+export type JSONMode = 'relaxed' | 'canonical' | 'shell';
+
+export type ColumnType = 'string' | 'int' | 'float' | 'bool' | 'time';
+
+export type EpochUnit = 's' | 'ms' | 'us' | 'ns';
+
+export interface Column {
+  name: string;
+  type?: ColumnType;
+  layout?: string;
+  unit?: EpochUnit;
+}
+
+export interface OutputColumn {
+  name: string;
+  alias?: string;
+}
+
+export type ColumnOrder = 'firstSeen' | 'alphabetical' | 'projection';
+
+export interface FieldConfig {
+  displayName?: string;
+  description?: string;
+  unit?: string;
+  decimals?: number;
+  min?: number;
+  max?: number;
+}
+
+export type FillMode = 'null' | 'previous' | 'zero' | 'linear';
+
+export type Aggregation = 'last' | 'mean' | 'sum' | 'min' | 'max';
+
+export type DownsampleMode = 'lttb' | 'minmax';
+
+export type Format = 'table' | 'time_series' | 'logs' | 'trace' | 'nodeGraph' | 'histogram' | 'heatmap';
+
 export interface MongoDBQuery extends DataQuery {
   queryText: string;
+  format?: Format;
+  decimal128?: Decimal128Mode;
+  exactDecimalFields?: string[];
+  seriesBy?: string[];
+  displayName?: string;
+  timeField?: string;
+  bodyField?: string;
+  severityField?: string;
+  traceIdField?: string;
+  spanIdField?: string;
+  parentSpanIdField?: string;
+  serviceNameField?: string;
+  operationNameField?: string;
+  startTimeField?: string;
+  durationField?: string;
+  durationUnit?: 'ns' | 'us' | 'ms' | 's';
+  tagsField?: string;
+  idField?: string;
+  titleField?: string;
+  subtitleField?: string;
+  mainStatField?: string;
+  secondaryStatField?: string;
+  parentField?: string;
+  edgesField?: string;
+  bucketField?: string;
+  countField?: string;
+  documentField?: string;
+  documentJson?: JSONMode;
+  objectIdTime?: boolean;
+  objectIdTimeFields?: string[];
+  geoJson?: boolean;
+  geoJsonFields?: string[];
+  schema?: Column[];
+  columns?: OutputColumn[];
+  excludeColumns?: string[];
+  onlyColumns?: boolean;
+  columnOrder?: ColumnOrder;
+  fieldConfig?: Record<string, FieldConfig>;
+  resample?: boolean;
+  resampleStep?: string;
+  fillMode?: FillMode;
+  aggregation?: Aggregation;
+  downsample?: DownsampleMode;
+  pivotKey?: string;
+  pivotValue?: string;
+  pivotAggregation?: Aggregation;
+  facets?: boolean;
+  childFrames?: string[];
 }
 
 export const defaultQuery: Partial<MongoDBQuery> = {
@@ -13,6 +100,8 @@ export const defaultQuery: Partial<MongoDBQuery> = {
  */
 export interface MongoDBDataSourceOptions extends DataSourceJsonData {
   maxResults: number;
+  maxResultBytes?: number;
+  uuidRepresentation?: 'pythonLegacy' | 'javaLegacy' | 'csharpLegacy';
 }
 
 /**